@@ -7,6 +7,7 @@ import (
 
 	"qiniuyun/backend/app/internal/config"
 	"qiniuyun/backend/app/internal/handler"
+	"qiniuyun/backend/app/internal/jobs"
 	"qiniuyun/backend/app/internal/svc"
 
 	"github.com/zeromicro/go-zero/core/conf"
@@ -27,6 +28,10 @@ func main() {
 	ctx := svc.NewServiceContext(c)
 	handler.RegisterHandlers(server, ctx)
 
+	jobs.Register(ctx)
+	ctx.Jobs.Start()
+	defer ctx.Jobs.Stop()
+
 	fmt.Printf("Starting server at %s:%d...\n", c.Host, c.Port)
 	server.Start()
 }
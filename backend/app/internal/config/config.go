@@ -17,27 +17,34 @@ type Config struct {
 		Password string
 	}
 	Kafka struct {
-		Topic string
-		Addr  []string
+		Topic string   `json:",default=roletalk-job"`
+		Addr  []string `json:",optional"`
 	}
+	Job          Job
 	Mysql        mysql.Mysql
 	CacheRedis   cache.CacheConf
 	EmailService EmailService
 	LLM          LLM
 	Qdrant       struct {
-		Host string
-		Port int
+		Host string `json:",default=127.0.0.1"`
+		Port int    `json:",default=6334"`
 	}
 	Embedding struct {
-		BaseURL string
+		Provider    string `json:",default=http,options=http|hash"`       // hash 为不依赖模型的哈希向量化
+		BaseURL     string `json:",optional"`                             // Python Embedding 服务地址，http 使用
+		Dimension   int    `json:",default=384"`                          // 哈希向量维度，hash 使用
+		VectorStore string `json:",default=qdrant,options=qdrant|memory"` // memory 为进程内存储，重启后丢失
 	}
 	Ali struct {
 		AccessKey string
 		SecretKey string
+		AppKey    string `json:",optional"` // 智能语音交互项目的 AppKey，服务端语音识别使用
 	}
+	STT   STT
 	Qiniu struct {
 		AccessKey string
 		SecretKey string
+		Domain    string `json:",optional"` // 头像等上传文件所在的 CDN 域名，导出 PNG 角色卡时只下载该域名下的头像
 	}
 }
 
@@ -50,7 +57,24 @@ type EmailService struct {
 }
 
 type LLM struct {
-	ApiKey  string
-	BaseURL string
-	Model   string
+	Provider      string `json:",default=openai,options=openai|fake"`
+	ApiKey        string
+	BaseURL       string
+	Model         string
+	FakeReplies   []string `json:",optional"`      // fake 模型按顺序循环返回的回复
+	ContextLimit  int      `json:",default=32768"` // 模型的上下文长度上限
+	ContextBudget int      `json:",default=6000"`  // 每次对话发送给模型的上下文最多占用的 token 数
+	ReplyTokens   int      `json:",default=1024"`  // 在上下文长度上限中为回复预留的 token 数
+}
+
+type Job struct {
+	Queue       string `json:",default=kafka,options=kafka|redis|memory"` // Kafka 未配置地址时使用 redis
+	Group       string `json:",default=roletalk-job"`                     // Kafka 消费组
+	Workers     int    `json:",default=4"`
+	MaxAttempts int    `json:",default=5"` // 每个任务最多执行的次数，之后进入死信
+}
+
+type STT struct {
+	Provider string `json:",default=ali,options=ali|fake"`
+	FakeText string `json:",optional"` // fake 识别器固定返回的文本
 }
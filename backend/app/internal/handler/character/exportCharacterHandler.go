@@ -0,0 +1,41 @@
+package character
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"qiniuyun/backend/common/response"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"qiniuyun/backend/app/internal/logic/character"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+)
+
+func ExportCharacterHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.ExportCharacterRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			response.ParamErrorResult(r, w, err)
+			return
+		}
+
+		err := svcCtx.Validate.StructCtx(r.Context(), req)
+		if err != nil {
+			response.Response(r, w, nil, err)
+			return
+		}
+
+		l := character.NewExportCharacterLogic(r.Context(), svcCtx)
+		file, err := l.ExportCharacter(&req)
+		if err != nil {
+			response.Response(r, w, nil, err)
+			return
+		}
+		// 直接返回文件而不是 JSON 响应，便于浏览器下载
+		w.Header().Set("Content-Type", file.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(file.Name)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(file.Data)
+	}
+}
@@ -15,6 +15,7 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // 允许所有来源
 	},
+	Subprotocols: []string{chat.BinaryAudioProtocol},
 }
 
 func ChatHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
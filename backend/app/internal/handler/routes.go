@@ -7,6 +7,7 @@ import (
 	character "qiniuyun/backend/app/internal/handler/character"
 	chat "qiniuyun/backend/app/internal/handler/chat"
 	user "qiniuyun/backend/app/internal/handler/user"
+	voice "qiniuyun/backend/app/internal/handler/voice"
 	"qiniuyun/backend/app/internal/svc"
 
 	"github.com/zeromicro/go-zero/rest"
@@ -57,6 +58,66 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 					Path:    "/character",
 					Handler: character.GetCharacterHandler(serverCtx),
 				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/character/:id",
+					Handler: character.GetCharacterDetailHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/character/:id",
+					Handler: character.UpdateCharacterHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodDelete,
+					Path:    "/character/:id",
+					Handler: character.DeleteCharacterHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/character/:id/status",
+					Handler: character.GetCharacterStatusHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/character/:id/regenerate",
+					Handler: character.RegenerateCharacterHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/character/:id/voice",
+					Handler: character.UpdateCharacterVoiceHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/character/:id/lorebook",
+					Handler: character.GetLorebookHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/character/:id/lorebook",
+					Handler: character.NewLorebookEntryHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/character/:id/lorebook/:entry_id",
+					Handler: character.UpdateLorebookEntryHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodDelete,
+					Path:    "/character/:id/lorebook/:entry_id",
+					Handler: character.DeleteLorebookEntryHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/character/import",
+					Handler: character.ImportCharacterHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/character/:id/export",
+					Handler: character.ExportCharacterHandler(serverCtx),
+				},
 			}...,
 		),
 		rest.WithPrefix("/api"),
@@ -76,6 +137,61 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 					Path:    "/session",
 					Handler: chat.GetSessionHandler(serverCtx),
 				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/messages/search",
+					Handler: chat.SearchMessagesHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/message/:id/select",
+					Handler: chat.SelectMessageHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/message/:id/branches",
+					Handler: chat.GetBranchesHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/session/:id",
+					Handler: chat.UpdateSessionHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodDelete,
+					Path:    "/session/:id",
+					Handler: chat.DeleteSessionHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/session/:id/archive",
+					Handler: chat.ArchiveSessionHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/session/:id/pin",
+					Handler: chat.PinSessionHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/session/:id/messages",
+					Handler: chat.GetMessagesHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/session/:id/summary",
+					Handler: chat.GetSummaryHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/session/:id/summary",
+					Handler: chat.UpdateSummaryHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/session/:id/persona",
+					Handler: chat.SetSessionPersonaHandler(serverCtx),
+				},
 			}...,
 		),
 		rest.WithPrefix("/api"),
@@ -140,6 +256,26 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 					Path:    "/user",
 					Handler: user.UpdateUserinfoHandler(serverCtx),
 				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/personas",
+					Handler: user.GetPersonasHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/persona",
+					Handler: user.NewPersonaHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPut,
+					Path:    "/persona/:id",
+					Handler: user.UpdatePersonaHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodDelete,
+					Path:    "/persona/:id",
+					Handler: user.DeletePersonaHandler(serverCtx),
+				},
 				{
 					Method:  http.MethodPut,
 					Path:    "/user/password",
@@ -149,4 +285,29 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 		),
 		rest.WithPrefix("/api"),
 	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/voices",
+				Handler: voice.GetVoicesHandler(serverCtx),
+			},
+		},
+		rest.WithPrefix("/api"),
+	)
+
+	server.AddRoutes(
+		rest.WithMiddlewares(
+			[]rest.Middleware{serverCtx.Auth},
+			[]rest.Route{
+				{
+					Method:  http.MethodPost,
+					Path:    "/voice/preview",
+					Handler: voice.PreviewVoiceHandler(serverCtx),
+				},
+			}...,
+		),
+		rest.WithPrefix("/api"),
+	)
 }
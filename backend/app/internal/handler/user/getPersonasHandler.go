@@ -0,0 +1,17 @@
+package user
+
+import (
+	"net/http"
+	"qiniuyun/backend/common/response"
+
+	"qiniuyun/backend/app/internal/logic/user"
+	"qiniuyun/backend/app/internal/svc"
+)
+
+func GetPersonasHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := user.NewGetPersonasLogic(r.Context(), svcCtx)
+		resp, err := l.GetPersonas()
+		response.Response(r, w, resp, err)
+	}
+}
@@ -0,0 +1,15 @@
+package jobs
+
+import (
+	"qiniuyun/backend/app/internal/logic/character"
+	"qiniuyun/backend/app/internal/logic/chat"
+	"qiniuyun/backend/app/internal/svc"
+)
+
+// Register 注册所有后台任务的 Handler
+func Register(svcCtx *svc.ServiceContext) {
+	svcCtx.Jobs.Handle(character.JobGenerate, character.HandleGenerate(svcCtx))
+	svcCtx.Jobs.Handle(chat.JobExtractMemories, chat.HandleExtractMemories(svcCtx))
+	svcCtx.Jobs.Handle(chat.JobSummarize, chat.HandleSummarize(svcCtx))
+	svcCtx.Jobs.Handle(chat.JobGenerateTitle, chat.HandleGenerateTitle(svcCtx))
+}
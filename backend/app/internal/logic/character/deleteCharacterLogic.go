@@ -0,0 +1,59 @@
+package character
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/common/globalkey"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type DeleteCharacterLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDeleteCharacterLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DeleteCharacterLogic {
+	return &DeleteCharacterLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// DeleteCharacter 软删除角色及其标签关联，并删除角色的全部向量记忆，仅角色创建者可删除
+func (l *DeleteCharacterLogic) DeleteCharacter(req *types.DeleteCharacterRequest) error {
+	character, err := findOwnedCharacter(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	characterTags, err := l.svcCtx.CharacterTagModel.FindByCharacter(l.ctx, character.Id)
+	if err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	err = l.svcCtx.CharacterModel.Transaction(l.ctx, func(db *gorm.DB) error {
+		if e := l.svcCtx.CharacterModel.Delete(l.ctx, db, character.Id); e != nil {
+			return e
+		}
+		for _, ct := range characterTags {
+			if e := l.svcCtx.CharacterTagModel.Delete(l.ctx, db, ct.Id); e != nil {
+				return e
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	// 角色已删除，向量记忆删除失败不影响结果
+	if err = l.svcCtx.Embedding.Drop(l.ctx, globalkey.Collection(character.Id)); err != nil {
+		l.Error(err)
+	}
+	return nil
+}
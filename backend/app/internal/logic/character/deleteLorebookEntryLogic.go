@@ -0,0 +1,37 @@
+package character
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type DeleteLorebookEntryLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDeleteLorebookEntryLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DeleteLorebookEntryLogic {
+	return &DeleteLorebookEntryLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *DeleteLorebookEntryLogic) DeleteLorebookEntry(req *types.DeleteLorebookEntryRequest) error {
+	entry, err := findOwnedLorebookEntry(l.ctx, l.svcCtx, req.CharacterId, req.EntryId)
+	if err != nil {
+		return err
+	}
+	if err = l.svcCtx.LorebookModel.Delete(l.ctx, nil, entry.Id); err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "entryId: %v,err: %+v", req.EntryId, err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package character
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"qiniuyun/backend/common/card"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+)
+
+const (
+	// maxAvatarSize 导出 PNG 角色卡时下载头像的大小上限
+	maxAvatarSize = 10 << 20
+)
+
+// avatarClient 下载头像的客户端：不跟随重定向，且只连接公网地址，防止通过头像地址访问内网
+var avatarClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: dialPublicOnly}).DialContext,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// dialPublicOnly 拒绝连接回环、内网、链路本地等非公网地址
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("avatar address %s is not public", address)
+	}
+	return nil
+}
+
+// CharacterFile 导出的角色卡文件
+type CharacterFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type ExportCharacterLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewExportCharacterLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ExportCharacterLogic {
+	return &ExportCharacterLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ExportCharacter 导出 Character Card V2，只能导出自己的或公开的角色。
+// PNG 格式将角色卡写入头像图片，没有可用头像时使用纯色图片
+func (l *ExportCharacterLogic) ExportCharacter(req *types.ExportCharacterRequest) (*CharacterFile, error) {
+	userId := ctxdata.GetUidFromCtx(l.ctx)
+	character, err := l.svcCtx.CharacterModel.FindOneByUser(l.ctx, req.Id, userId)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	c, err := l.buildCard(character)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Format == "json" {
+		data, err := c.JSON()
+		if err != nil {
+			return nil, errors.Wrapf(errorz.NewErrCode(errorz.SERVER_COMMON_ERROR), "characterId: %v,err: %+v", req.Id, err)
+		}
+		return &CharacterFile{Name: character.Name + ".json", ContentType: "application/json", Data: data}, nil
+	}
+	data, err := card.WritePNG(l.avatar(character.AvatarUrl), c)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.SERVER_COMMON_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	return &CharacterFile{Name: character.Name + ".png", ContentType: "image/png", Data: data}, nil
+}
+
+func (l *ExportCharacterLogic) buildCard(character *model.Character) (*card.Card, error) {
+	data := card.Data{
+		Name:             character.Name,
+		Description:      character.Description,
+		Personality:      strings.Join(character.Personality, ", "),
+		Scenario:         character.Background,
+		FirstMes:         character.OpenLine,
+		MesExample:       character.ExampleDialogue,
+		SystemPrompt:     character.SystemPrompt,
+		CharacterVersion: "1.0",
+	}
+	if user, err := l.svcCtx.UserModel.FindOne(l.ctx, character.UserId); err == nil {
+		data.Creator = user.Name
+	}
+
+	tags, err := findTagNames(l.ctx, l.svcCtx, character.Id)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", character.Id, err)
+	}
+	data.Tags = tags
+
+	entries, err := l.svcCtx.LorebookModel.FindByCharacter(l.ctx, character.Id)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", character.Id, err)
+	}
+	if len(entries) > 0 {
+		data.CharacterBook = &card.Book{}
+		for _, entry := range entries {
+			position := card.PositionAfterChar
+			if entry.Position == "before" {
+				position = card.PositionBeforeChar
+			}
+			data.CharacterBook.Entries = append(data.CharacterBook.Entries, card.BookEntry{
+				Keys:           entry.Keywords,
+				Content:        entry.Content,
+				Enabled:        entry.Enabled == 1,
+				InsertionOrder: entry.Priority,
+				Priority:       entry.Priority,
+				Id:             entry.Id,
+				Position:       position,
+			})
+		}
+	}
+	return card.New(data), nil
+}
+
+// avatar 下载头像并转为 PNG，头像不在配置的 CDN 域名下或下载失败时返回纯色图片
+func (l *ExportCharacterLogic) avatar(avatarUrl string) []byte {
+	if !l.isUploadedAvatar(avatarUrl) {
+		return card.Placeholder()
+	}
+	req, err := http.NewRequestWithContext(l.ctx, http.MethodGet, avatarUrl, nil)
+	if err != nil {
+		l.Error(err)
+		return card.Placeholder()
+	}
+	resp, err := avatarClient.Do(req)
+	if err != nil {
+		l.Error(err)
+		return card.Placeholder()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		l.Errorf("download avatar %s: %s", avatarUrl, resp.Status)
+		return card.Placeholder()
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxAvatarSize))
+	if err != nil {
+		l.Error(err)
+		return card.Placeholder()
+	}
+	img, err := card.ToPNG(b)
+	if err != nil {
+		l.Error(err)
+		return card.Placeholder()
+	}
+	return img
+}
+
+// isUploadedAvatar 头像地址是否为上传到配置的 CDN 域名下的文件
+func (l *ExportCharacterLogic) isUploadedAvatar(avatarUrl string) bool {
+	domain := l.svcCtx.Config.Qiniu.Domain
+	if avatarUrl == "" || domain == "" {
+		return false
+	}
+	u, err := url.Parse(avatarUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil {
+		return false
+	}
+	return strings.EqualFold(u.Hostname(), domain)
+}
+
+// findTagNames 按关联的先后顺序返回角色全部标签的名称，已删除的标签不返回
+func findTagNames(ctx context.Context, svcCtx *svc.ServiceContext, characterId int64) ([]string, error) {
+	characterTags, err := svcCtx.CharacterTagModel.FindByCharacter(ctx, characterId)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(characterTags))
+	for _, ct := range characterTags {
+		ids = append(ids, ct.TagId)
+	}
+	tags, err := svcCtx.TagModel.FindByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(tags))
+	for _, tag := range tags {
+		names[tag.Id] = tag.Name
+	}
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,131 @@
+package character
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/common/embedding"
+	"qiniuyun/backend/common/globalkey"
+	"qiniuyun/backend/common/job"
+	"qiniuyun/backend/model"
+	"time"
+)
+
+const (
+	// JobGenerate 生成角色缺少的性格、初始记忆与系统提示词，并用初始记忆重建角色的共享记忆
+	JobGenerate = "character.generate"
+	// maxGenerateRounds 一次任务中因角色被修改而重新生成的最多轮数，超出后交给任务重试
+	maxGenerateRounds = 3
+)
+
+type generatePayload struct {
+	CharacterId int64 `json:"character_id"`
+	// UserId 角色创建者，私有角色只能由创建者查询
+	UserId int64 `json:"user_id"`
+}
+
+// startGenerate 提交角色生成任务，提交失败时将角色标记为生成失败，可通过重新生成接口重试。
+// 同一角色同时只有一个任务在排队或执行，执行期间的修改由任务通过生成版本发现并重新生成，
+// 任务释放唯一键之前的提交会在任务结束后再执行一次
+func startGenerate(ctx context.Context, svcCtx *svc.ServiceContext, character *model.Character) {
+	characterId := character.Id
+	err := svcCtx.Jobs.Enqueue(ctx, JobGenerate, fmt.Sprintf("character:%d", characterId), generatePayload{
+		CharacterId: characterId,
+		UserId:      character.UserId,
+	})
+	if err == nil {
+		return
+	}
+	logx.WithContext(ctx).Errorf("enqueue generate character %d: %+v", characterId, err)
+	if err = svcCtx.CharacterModel.UpdateStatus(ctx, characterId, model.CharacterStatusFailed, statusReason(err)); err != nil {
+		logx.WithContext(ctx).Error(err)
+	}
+}
+
+// HandleGenerate 执行角色生成任务，生成过程写入角色的生成状态。只生成缺少的部分，重复执行结果一致；
+// 生成期间角色被修改时按修改后的内容重新生成，不覆盖修改。
+// 失败后任务还会重试时状态保持为等待生成，最后一次失败时标记为生成失败并记录原因
+func HandleGenerate(svcCtx *svc.ServiceContext) job.Handler {
+	return func(ctx context.Context, j *job.Job) error {
+		var payload generatePayload
+		if err := j.Bind(&payload); err != nil {
+			return err
+		}
+		for i := 0; i < maxGenerateRounds; i++ {
+			character, err := svcCtx.CharacterModel.FindOneByUser(ctx, payload.CharacterId, payload.UserId)
+			if errors.Is(err, model.ErrNotFound) {
+				// 角色已删除
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err = svcCtx.CharacterModel.UpdateStatus(ctx, character.Id, model.CharacterStatusGenerating, ""); err != nil {
+				return err
+			}
+			current, err := generateAndIndex(ctx, svcCtx, character)
+			if err != nil {
+				status := model.CharacterStatusPending
+				if j.Final() {
+					status = model.CharacterStatusFailed
+				}
+				if e := svcCtx.CharacterModel.UpdateStatus(ctx, character.Id, status, statusReason(err)); e != nil {
+					logx.WithContext(ctx).Error(e)
+				}
+				return err
+			}
+			if current {
+				return nil
+			}
+		}
+		return fmt.Errorf("character %d keeps changing during generation", payload.CharacterId)
+	}
+}
+
+// generateAndIndex 生成缺少的部分并重建共享记忆，最后将角色标记为就绪。
+// 写入均以生成版本为条件，版本已变时返回 false，由调用方重新读取角色后再次生成
+func generateAndIndex(ctx context.Context, svcCtx *svc.ServiceContext, character *model.Character) (bool, error) {
+	err := generateCharacterData(svcCtx.LLM, character)
+	if err != nil {
+		return false, err
+	}
+	current, err := svcCtx.CharacterModel.UpdateIfGeneration(ctx, character.Id, character.Generation, map[string]interface{}{
+		"personality":    character.Personality,
+		"initial_memory": character.InitialMemory,
+		"system_prompt":  character.SystemPrompt,
+	})
+	if err != nil || !current {
+		return false, err
+	}
+	vectors, err := svcCtx.Embedding.GetEmbeddings(ctx, character.InitialMemory)
+	if err != nil {
+		return false, fmt.Errorf("embed initial memory: %w", err)
+	}
+	// 先删除旧的共享记忆再写入，角色修改背景后据此重建，用户的记忆不受影响
+	collection := globalkey.Collection(character.Id)
+	if err = svcCtx.Embedding.DeleteShared(ctx, collection); err != nil {
+		return false, fmt.Errorf("delete initial memory: %w", err)
+	}
+	err = svcCtx.Embedding.InsertVectors(ctx, collection, character.InitialMemory, vectors, embedding.Payload{
+		Source:    embedding.SourceCharacter,
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("insert initial memory: %w", err)
+	}
+	return svcCtx.CharacterModel.UpdateIfGeneration(ctx, character.Id, character.Generation, map[string]interface{}{
+		"status":        model.CharacterStatusReady,
+		"status_reason": "",
+	})
+}
+
+// statusReason 失败原因最多保留 255 个字符
+func statusReason(err error) string {
+	reason := []rune(err.Error())
+	if len(reason) > 255 {
+		reason = reason[:255]
+	}
+	return string(reason)
+}
@@ -0,0 +1,46 @@
+package character
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/model"
+)
+
+// 生成任务已将角色标记为就绪、尚未释放唯一键时角色被修改，修改后的角色仍会重新生成
+func TestGenerateRerunsAfterUpdateBeforeRelease(t *testing.T) {
+	characters := &fakeCharacterModel{characters: make(map[int64]*model.Character)}
+	svcCtx := newTestServiceContext(t, characters, &fakeCharacterTagModel{})
+
+	ctx := context.WithValue(context.Background(), ctxdata.CtxKeyJwtUserId, int64(1))
+	var generation atomic.Int64
+	characters.onReady = func(id int64) {
+		if generation.Load() != 0 {
+			return
+		}
+		character := characters.update(id, func(c *model.Character) {
+			c.Background = "在雪山下的小镇长大"
+			c.Personality, c.InitialMemory, c.SystemPrompt = nil, nil, ""
+		})
+		generation.Store(character.Generation)
+		startGenerate(ctx, svcCtx, character)
+	}
+	resp, err := NewNewCharacterLogic(ctx, svcCtx).NewCharacter(&types.NewCharacterRequest{
+		Name:        "艾拉",
+		Description: "喜欢下雨天的旅行者",
+		Background:  "在雨季的港口长大",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	character := waitCharacter(t, characters, resp.Character.Id, "regenerated character", func(c model.Character) bool {
+		return generation.Load() != 0 && c.Generation == generation.Load() && c.Status == model.CharacterStatusReady
+	})
+	if character.SystemPrompt == "" || len(character.Personality) == 0 {
+		t.Fatalf("updated character not regenerated: %+v", character)
+	}
+}
@@ -0,0 +1,80 @@
+package character
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetCharacterDetailLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetCharacterDetailLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetCharacterDetailLogic {
+	return &GetCharacterDetailLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// GetCharacterDetail 角色详情，包括标签、创建者与对话统计，私有角色仅创建者可见
+func (l *GetCharacterDetailLogic) GetCharacterDetail(req *types.GetCharacterDetailRequest) (resp *types.GetCharacterDetailResponse, err error) {
+	userId := ctxdata.GetUidFromCtx(l.ctx)
+	character, err := l.svcCtx.CharacterModel.FindOneByUser(l.ctx, req.Id, userId)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.CHARACTER_NOT_FOUND_ERROR), "userId: %d, characterId: %d", userId, req.Id)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+
+	c := castCharacter(character)
+	c.Tags, err = findTagNames(l.ctx, l.svcCtx, character.Id)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+
+	// 创建者的账号已注销时不返回创建者
+	var creator types.User
+	user, err := l.svcCtx.UserModel.FindOne(l.ctx, character.UserId)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "userId: %v,err: %+v", character.UserId, err)
+	}
+	if err == nil {
+		c.UserName = user.Name
+		creator = types.User{
+			ID:        user.Id,
+			Name:      user.Name,
+			Avatar:    user.Avatar,
+			Signature: user.Signature.String,
+		}
+	}
+
+	sessions, chatters, err := l.svcCtx.SessionModel.CountByCharacter(l.ctx, character.Id)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	messages, err := l.svcCtx.MessageModel.CountByCharacter(l.ctx, character.Id)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	return &types.GetCharacterDetailResponse{
+		Character: c,
+		Creator:   creator,
+		Stats: types.CharacterStats{
+			SessionCount: sessions,
+			MessageCount: messages,
+			ChatterCount: chatters,
+		},
+	}, nil
+}
@@ -0,0 +1,109 @@
+package character
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+)
+
+func (m *fakeCharacterTagModel) FindByCharacter(ctx context.Context, characterId int64) ([]*model.CharacterTag, error) {
+	var res []*model.CharacterTag
+	for i := range m.tags {
+		if m.tags[i].CharacterId == characterId {
+			res = append(res, &m.tags[i])
+		}
+	}
+	return res, nil
+}
+
+// fakeTagModel 只保存标签名称，记录批量查询的次数
+type fakeTagModel struct {
+	model.TagModel
+	names   map[int64]string
+	queries int
+}
+
+func (m *fakeTagModel) FindByIds(ctx context.Context, ids []int64) ([]*model.Tag, error) {
+	m.queries++
+	var res []*model.Tag
+	for _, id := range ids {
+		if name, ok := m.names[id]; ok {
+			res = append(res, &model.Tag{Id: id, Name: name})
+		}
+	}
+	return res, nil
+}
+
+// fakeUserModel 没有任何用户，模拟创建者已注销
+type fakeUserModel struct {
+	model.UserModel
+}
+
+func (m *fakeUserModel) FindOne(ctx context.Context, id int64) (*model.User, error) {
+	return nil, model.ErrNotFound
+}
+
+type fakeSessionModel struct {
+	model.SessionModel
+}
+
+func (m *fakeSessionModel) CountByCharacter(ctx context.Context, characterId int64) (int64, int64, error) {
+	return 2, 1, nil
+}
+
+type fakeMessageModel struct {
+	model.MessageModel
+}
+
+func (m *fakeMessageModel) CountByCharacter(ctx context.Context, characterId int64) (int64, error) {
+	return 6, nil
+}
+
+func TestGetCharacterDetailSkipsMissingRows(t *testing.T) {
+	characters := &fakeCharacterModel{characters: map[int64]*model.Character{
+		1: {Id: 1, UserId: 2, IsPublic: 1},
+	}}
+	// 角色关联的标签超过 100 个，最后一个标签已被删除
+	tags := &fakeCharacterTagModel{}
+	names := make(map[int64]string)
+	var want []string
+	for id := int64(1); id <= 120; id++ {
+		tags.tags = append(tags.tags, model.CharacterTag{CharacterId: 1, TagId: id})
+		if id < 120 {
+			names[id] = fmt.Sprintf("标签%d", id)
+			want = append(want, names[id])
+		}
+	}
+	svcCtx := newTestServiceContext(t, characters, tags)
+	tagModel := &fakeTagModel{names: names}
+	svcCtx.TagModel = tagModel
+	svcCtx.UserModel = &fakeUserModel{}
+	svcCtx.SessionModel = &fakeSessionModel{}
+	svcCtx.MessageModel = &fakeMessageModel{}
+	logic := NewGetCharacterDetailLogic(context.WithValue(context.Background(), ctxdata.CtxKeyJwtUserId, int64(1)), svcCtx)
+
+	resp, err := logic.GetCharacterDetail(&types.GetCharacterDetailRequest{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(resp.Character.Tags, want) || resp.Creator.ID != 0 || resp.Stats.MessageCount != 6 {
+		t.Fatalf("detail = %+v", resp)
+	}
+	// 标签名称一次批量查询
+	if tagModel.queries != 1 {
+		t.Fatalf("tag queries = %d, want 1", tagModel.queries)
+	}
+
+	_, err = logic.GetCharacterDetail(&types.GetCharacterDetailRequest{Id: 2})
+	var codeErr *errorz.CodeError
+	if !errors.As(err, &codeErr) || codeErr.GetErrCode() != errorz.CHARACTER_NOT_FOUND_ERROR {
+		t.Fatalf("err = %v, want CHARACTER_NOT_FOUND_ERROR", err)
+	}
+}
@@ -84,5 +84,19 @@ func castCharacter(character *model.Character) (resp types.Character) {
 		Background:  character.Background,
 		OpenLine:    character.OpenLine,
 		Avatar:      character.AvatarUrl,
+		Voice:       character.Voice,
+		TTSConfig: types.TTSConfig{
+			Encoding:    character.TTSConfig.Encoding,
+			SpeedRatio:  character.TTSConfig.SpeedRatio,
+			PitchRatio:  character.TTSConfig.PitchRatio,
+			VolumeRatio: character.TTSConfig.VolumeRatio,
+			Emotion:     character.TTSConfig.Emotion,
+		},
+		Personality: character.Personality,
+		Status:      character.Status,
+		IsPublic:    character.IsPublic == 1,
+		UserId:      character.UserId,
+		CreatedAt:   character.CreatedAt.Unix(),
+		UpdatedAt:   character.UpdatedAt.Unix(),
 	}
 }
@@ -0,0 +1,36 @@
+package character
+
+import (
+	"context"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetCharacterStatusLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetCharacterStatusLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetCharacterStatusLogic {
+	return &GetCharacterStatusLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// GetCharacterStatus 查询角色的生成状态，供创建者在创建或修改角色后轮询
+func (l *GetCharacterStatusLogic) GetCharacterStatus(req *types.GetCharacterStatusRequest) (resp *types.GetCharacterStatusResponse, err error) {
+	character, err := findOwnedCharacter(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &types.GetCharacterStatusResponse{
+		Status: character.Status,
+		Reason: character.StatusReason,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package character
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetLorebookLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetLorebookLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetLorebookLogic {
+	return &GetLorebookLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetLorebookLogic) GetLorebook(req *types.GetLorebookRequest) (resp *types.GetLorebookResponse, err error) {
+	if _, err = findOwnedCharacter(l.ctx, l.svcCtx, req.CharacterId); err != nil {
+		return nil, err
+	}
+	entries, err := l.svcCtx.LorebookModel.FindByCharacter(l.ctx, req.CharacterId)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.CharacterId, err)
+	}
+	res := make([]types.LorebookEntry, 0, len(entries))
+	for _, entry := range entries {
+		res = append(res, castLorebookEntry(entry))
+	}
+	return &types.GetLorebookResponse{
+		Entries: res,
+	}, nil
+}
+
+func castLorebookEntry(entry *model.LorebookEntry) types.LorebookEntry {
+	return types.LorebookEntry{
+		Id:          entry.Id,
+		CharacterId: entry.CharacterId,
+		Keywords:    entry.Keywords,
+		Content:     entry.Content,
+		Priority:    entry.Priority,
+		Position:    entry.Position,
+		Enabled:     entry.Enabled == 1,
+		CreatedAt:   entry.CreatedAt.Unix(),
+		UpdatedAt:   entry.UpdatedAt.Unix(),
+	}
+}
+
+// findOwnedLorebookEntry 查找当前用户角色下的世界书条目
+func findOwnedLorebookEntry(ctx context.Context, svcCtx *svc.ServiceContext, characterId, entryId int64) (*model.LorebookEntry, error) {
+	if _, err := findOwnedCharacter(ctx, svcCtx, characterId); err != nil {
+		return nil, err
+	}
+	entry, err := svcCtx.LorebookModel.FindOne(ctx, entryId)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "entryId: %v,err: %+v", entryId, err)
+	}
+	if entry.CharacterId != characterId {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REQUEST_ROLE_ERROR), "characterId: %d, entryId: %d", characterId, entryId)
+	}
+	return entry, nil
+}
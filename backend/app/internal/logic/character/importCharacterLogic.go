@@ -0,0 +1,154 @@
+package character
+
+import (
+	"context"
+	"encoding/base64"
+	"qiniuyun/backend/common/card"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+)
+
+const (
+	// maxCardSize 导入角色卡文件的大小上限
+	maxCardSize = 10 << 20
+)
+
+type ImportCharacterLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewImportCharacterLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ImportCharacterLogic {
+	return &ImportCharacterLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ImportCharacter 从 PNG 或 JSON 角色卡创建角色。标签只关联已存在的标签，世界书条目写入角色的世界书；
+// 角色卡带有的系统提示词与性格保留，后台只生成缺少的部分，初始记忆总是生成并写入角色的共享记忆
+func (l *ImportCharacterLogic) ImportCharacter(req *types.ImportCharacterRequest) (resp *types.ImportCharacterResponse, err error) {
+	// 解码前按编码长度估计文件大小，超出上限的文件不分配内存
+	if size := base64.StdEncoding.DecodedLen(len(req.File)); size > maxCardSize {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "card file too large, size: %d", size)
+	}
+	file, err := base64.StdEncoding.DecodeString(req.File)
+	if err != nil || len(file) > maxCardSize {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "invalid card file, size: %d, err: %v", len(file), err)
+	}
+	c, err := card.Parse(file)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "parse card: %+v", err)
+	}
+
+	userId := ctxdata.GetUidFromCtx(l.ctx)
+	character := &model.Character{
+		UserId:          userId,
+		Name:            c.Data.Name,
+		Description:     c.Data.Description,
+		Background:      c.Data.Scenario,
+		OpenLine:        c.Data.FirstMes,
+		Personality:     splitTraits(c.Data.Personality),
+		SystemPrompt:    c.Data.SystemPrompt,
+		ExampleDialogue: c.Data.MesExample,
+		AvatarUrl:       req.Avatar,
+		IsPublic:        castBool(req.IsPublic),
+		Status:          model.CharacterStatusPending,
+	}
+	tags := make([]string, 0)
+	characterTags := make([]model.CharacterTag, 0)
+	for _, name := range c.Data.Tags {
+		tag, e := l.svcCtx.TagModel.FindOneByName(l.ctx, strings.TrimSpace(name))
+		if e != nil {
+			continue
+		}
+		tags = append(tags, tag.Name)
+		characterTags = append(characterTags, model.CharacterTag{TagId: tag.Id})
+	}
+	err = l.svcCtx.CharacterModel.Transaction(l.ctx, func(db *gorm.DB) error {
+		e := l.svcCtx.CharacterModel.Insert(l.ctx, db, character)
+		if e != nil {
+			return e
+		}
+		if len(characterTags) > 0 {
+			for i := range characterTags {
+				characterTags[i].CharacterId = character.Id
+			}
+			if e = l.svcCtx.CharacterTagModel.Inserts(l.ctx, db, &characterTags); e != nil {
+				return e
+			}
+		}
+		for _, entry := range castCardEntries(character.Id, c.Data.CharacterBook) {
+			if e = l.svcCtx.LorebookModel.Insert(l.ctx, db, entry); e != nil {
+				return e
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "import character: %s, err: %+v", character.Name, err)
+	}
+	startGenerate(l.ctx, l.svcCtx, character)
+
+	res := castCharacter(character)
+	res.Tags = tags
+	return &types.ImportCharacterResponse{
+		Character: res,
+	}, nil
+}
+
+// castCardEntries 将角色卡世界书转为世界书条目，没有关键词的条目无法触发，忽略
+func castCardEntries(characterId int64, book *card.Book) []*model.LorebookEntry {
+	if book == nil {
+		return nil
+	}
+	entries := make([]*model.LorebookEntry, 0, len(book.Entries))
+	for _, e := range book.Entries {
+		keywords := make([]string, 0, len(e.Keys))
+		for _, key := range e.Keys {
+			if key = strings.TrimSpace(key); key != "" {
+				keywords = append(keywords, key)
+			}
+		}
+		if len(keywords) == 0 || strings.TrimSpace(e.Content) == "" {
+			continue
+		}
+		position := "after"
+		if e.Position == card.PositionBeforeChar {
+			position = "before"
+		}
+		entries = append(entries, &model.LorebookEntry{
+			CharacterId: characterId,
+			Keywords:    keywords,
+			Content:     e.Content,
+			Priority:    e.InsertionOrder,
+			Position:    position,
+			Enabled:     castBool(e.Enabled),
+		})
+	}
+	return entries
+}
+
+// splitTraits 角色卡的性格为一段文本，按逗号、顿号、分号与换行拆分为性格标签
+func splitTraits(personality string) []string {
+	fields := strings.FieldsFunc(personality, func(r rune) bool {
+		return strings.ContainsRune(",，、;；\n", r)
+	})
+	traits := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			traits = append(traits, field)
+		}
+	}
+	return traits
+}
@@ -0,0 +1,60 @@
+package character
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/card"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/common/globalkey"
+	"qiniuyun/backend/model"
+)
+
+func TestImportCharacterWithPromptStillIndexesMemory(t *testing.T) {
+	characters := &fakeCharacterModel{characters: make(map[int64]*model.Character)}
+	svcCtx := newTestServiceContext(t, characters, &fakeCharacterTagModel{})
+	logic := NewImportCharacterLogic(context.WithValue(context.Background(), ctxdata.CtxKeyJwtUserId, int64(1)), svcCtx)
+
+	data, err := card.New(card.Data{
+		Name:         "艾拉",
+		Scenario:     "在雨季的港口长大",
+		Personality:  "温柔, 好奇",
+		SystemPrompt: "你是艾拉。",
+	}).JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := logic.ImportCharacter(&types.ImportCharacterRequest{File: base64.StdEncoding.EncodeToString(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 角色卡已有系统提示词与性格，仍需生成初始记忆并写入共享记忆
+	c := waitCharacter(t, characters, resp.Character.Id, "ready", func(c model.Character) bool {
+		return c.Status == model.CharacterStatusReady
+	})
+	if c.SystemPrompt != "你是艾拉。" || len(c.Personality) != 2 || len(c.InitialMemory) == 0 {
+		t.Fatalf("imported character = %+v", c)
+	}
+	vector, _ := svcCtx.Embedding.GetEmbedding(c.InitialMemory[0])
+	if memories, _ := svcCtx.Embedding.Search(globalkey.Collection(c.Id), vector, 2); len(memories) == 0 || memories[0].UserId != 0 {
+		t.Fatalf("shared memories = %+v", memories)
+	}
+}
+
+func TestImportCharacterRejectsOversizedFile(t *testing.T) {
+	svcCtx := newTestServiceContext(t, &fakeCharacterModel{characters: make(map[int64]*model.Character)}, &fakeCharacterTagModel{})
+	logic := NewImportCharacterLogic(context.WithValue(context.Background(), ctxdata.CtxKeyJwtUserId, int64(1)), svcCtx)
+
+	// 超出上限的文件在解码前被拒绝
+	_, err := logic.ImportCharacter(&types.ImportCharacterRequest{File: strings.Repeat("A", maxCardSize/3*4+8)})
+	var codeErr *errorz.CodeError
+	if !errors.As(err, &codeErr) || codeErr.GetErrCode() != errorz.REUQEST_PARAM_ERROR {
+		t.Fatalf("err = %v, want REUQEST_PARAM_ERROR", err)
+	}
+}
@@ -2,13 +2,15 @@ package character
 
 import (
 	"context"
+	"github.com/pkg/errors"
 	"github.com/zeromicro/go-zero/core/logx"
 	"gorm.io/gorm"
 	"qiniuyun/backend/app/internal/svc"
 	"qiniuyun/backend/app/internal/types"
 	"qiniuyun/backend/common/ctxdata"
-	"qiniuyun/backend/common/globalkey"
+	"qiniuyun/backend/common/errorz"
 	"qiniuyun/backend/common/llm"
+	"qiniuyun/backend/common/tts"
 	"qiniuyun/backend/model"
 )
 
@@ -27,6 +29,9 @@ func NewNewCharacterLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NewC
 }
 
 func (l *NewCharacterLogic) NewCharacter(req *types.NewCharacterRequest) (resp *types.NewCharacterResponse, err error) {
+	if err = checkVoice(req.Voice); err != nil {
+		return nil, err
+	}
 	userId := ctxdata.GetUidFromCtx(l.ctx)
 	character := &model.Character{
 		UserId:      userId,
@@ -36,7 +41,9 @@ func (l *NewCharacterLogic) NewCharacter(req *types.NewCharacterRequest) (resp *
 		OpenLine:    req.OpenLine,
 		AvatarUrl:   req.Avatar,
 		Voice:       req.Voice,
+		TTSConfig:   castModelTTSConfig(req.TTSConfig),
 		IsPublic:    castBool(req.IsPublic),
+		Status:      model.CharacterStatusPending,
 	}
 	err = l.svcCtx.CharacterModel.Transaction(l.ctx, func(db *gorm.DB) error {
 		e := l.svcCtx.CharacterModel.Insert(l.ctx, db, character)
@@ -57,52 +64,54 @@ func (l *NewCharacterLogic) NewCharacter(req *types.NewCharacterRequest) (resp *
 		return nil, err
 	}
 	// 异步生成并更新
-	go func() {
-		personality, memory, systemPrompt, err := generateCharacterData(l.svcCtx.LLM, req)
-		if err != nil {
-			logx.Error(err)
-			return
-		}
-		character.Personality = personality
-		character.InitialMemory = memory
-		character.SystemPrompt = systemPrompt
-		err = l.svcCtx.CharacterModel.Update(context.Background(), nil, character)
-		if err != nil {
-			logx.Error(err)
-		}
-		var vectors [][]float32
-		for _, m := range memory {
-			vector, e := l.svcCtx.Embedding.GetEmbedding(m)
-			if e != nil {
-				logx.Error(e)
-				return
-			}
-			vectors = append(vectors, vector)
-		}
-		err = l.svcCtx.Embedding.InsertVectors(context.Background(), globalkey.Collection(character.Id), memory, vectors)
-		if err != nil {
-			logx.Error(err)
-			return
-		}
-	}()
+	startGenerate(l.ctx, l.svcCtx, character)
 	return &types.NewCharacterResponse{
 		Character: castCharacter(character),
 	}, nil
 }
 
-func generateCharacterData(llm *llm.Client, req *types.NewCharacterRequest) (personality, memory []string, systemPrompt string, err error) {
-	personality, err = llm.GeneratePersonality(req.Description, req.Background)
-	if err != nil {
-		return
+// generateCharacterData 生成角色缺少的性格、初始记忆与系统提示词，已有的保持不变。{{char}} 在生成前替换为角色名，
+// {{user}} 保留在生成结果中，对话时再替换为用户的称呼
+func generateCharacterData(client *llm.Client, character *model.Character) (err error) {
+	placeholders := llm.Placeholders{Char: character.Name}
+	description, background := placeholders.Replace(character.Description), placeholders.Replace(character.Background)
+	if len(character.Personality) == 0 {
+		if character.Personality, err = client.GeneratePersonality(description, background); err != nil {
+			return
+		}
 	}
-	memory, err = llm.GenerateInitialMemory(req.Background)
-	if err != nil {
-		return
+	if len(character.InitialMemory) == 0 {
+		if character.InitialMemory, err = client.GenerateInitialMemory(background); err != nil {
+			return
+		}
+	}
+	if character.SystemPrompt == "" {
+		character.SystemPrompt, err = client.GenerateSystemPrompt(character.Name, description, character.Personality)
 	}
-	systemPrompt, err = llm.GenerateSystemPrompt(req.Name, req.Description, personality)
 	return
 }
 
+// checkVoice 音色可以为空，不为空时必须是音色目录中的音色
+func checkVoice(voice string) error {
+	if voice == "" {
+		return nil
+	}
+	if _, ok := tts.FindVoice(voice); !ok {
+		return errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "voice: %s", voice)
+	}
+	return nil
+}
+
+func castModelTTSConfig(c types.TTSConfig) model.TTSConfig {
+	return model.TTSConfig{
+		Encoding:    c.Encoding,
+		SpeedRatio:  c.SpeedRatio,
+		PitchRatio:  c.PitchRatio,
+		VolumeRatio: c.VolumeRatio,
+		Emotion:     c.Emotion,
+	}
+}
+
 func castBool(b bool) int64 {
 	if b {
 		return 1
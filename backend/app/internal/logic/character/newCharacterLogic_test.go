@@ -0,0 +1,218 @@
+package character
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/embedding"
+	"qiniuyun/backend/common/globalkey"
+	"qiniuyun/backend/common/job"
+	"qiniuyun/backend/common/llm"
+	"qiniuyun/backend/model"
+)
+
+// fakeCharacterModel 测试用的内存模型，只实现创建与生成角色用到的方法，其余方法调用时 panic
+type fakeCharacterModel struct {
+	model.CharacterModel
+	mu         sync.Mutex
+	characters map[int64]*model.Character
+	// onReady 角色被标记为就绪后调用
+	onReady func(id int64)
+}
+
+func (m *fakeCharacterModel) Transaction(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(nil)
+}
+
+func (m *fakeCharacterModel) Insert(ctx context.Context, tx *gorm.DB, data *model.Character) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data.Id = int64(len(m.characters) + 1)
+	res := *data
+	m.characters[data.Id] = &res
+	return nil
+}
+
+func (m *fakeCharacterModel) Update(ctx context.Context, tx *gorm.DB, data *model.Character) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := *data
+	m.characters[data.Id] = &res
+	return nil
+}
+
+func (m *fakeCharacterModel) FindOneByUser(ctx context.Context, id int64, userId int64) (*model.Character, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.characters[id]; ok && (c.IsPublic == 1 || c.UserId == userId) {
+		res := *c
+		return &res, nil
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *fakeCharacterModel) UpdateIfGeneration(ctx context.Context, id int64, generation int64, columns map[string]interface{}) (bool, error) {
+	m.mu.Lock()
+	c := m.characters[id]
+	if c.Generation != generation {
+		m.mu.Unlock()
+		return false, nil
+	}
+	for column, value := range columns {
+		switch column {
+		case "personality":
+			c.Personality = value.(model.StringArray)
+		case "initial_memory":
+			c.InitialMemory = value.(model.StringArray)
+		case "system_prompt":
+			c.SystemPrompt = value.(string)
+		case "status":
+			c.Status = value.(int64)
+		case "status_reason":
+			c.StatusReason = value.(string)
+		}
+	}
+	m.mu.Unlock()
+	if c.Status == model.CharacterStatusReady && m.onReady != nil {
+		m.onReady(id)
+	}
+	return true, nil
+}
+
+func (m *fakeCharacterModel) UpdateStatus(ctx context.Context, id int64, status int64, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.characters[id].Status = status
+	m.characters[id].StatusReason = reason
+	return nil
+}
+
+// update 修改角色并使生成版本失效，与 UpdateCharacter 修改背景时一致
+func (m *fakeCharacterModel) update(id int64, fn func(c *model.Character)) *model.Character {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.characters[id]
+	fn(c)
+	c.Status, c.StatusReason = model.CharacterStatusPending, ""
+	c.Generation++
+	res := *c
+	return &res
+}
+
+func (m *fakeCharacterModel) find(id int64) model.Character {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *m.characters[id]
+}
+
+type fakeCharacterTagModel struct {
+	model.CharacterTagModel
+	tags []model.CharacterTag
+}
+
+func (m *fakeCharacterTagModel) Inserts(ctx context.Context, tx *gorm.DB, data *[]model.CharacterTag) error {
+	m.tags = append(m.tags, *data...)
+	return nil
+}
+
+func newTestServiceContext(t *testing.T, characters *fakeCharacterModel, tags *fakeCharacterTagModel) *svc.ServiceContext {
+	svcCtx := &svc.ServiceContext{
+		CharacterModel:    characters,
+		CharacterTagModel: tags,
+		LLM:               llm.New(llm.NewFake(nil)),
+		Embedding:         embedding.New(embedding.NewHashEmbedder(256), embedding.NewMemoryStore()),
+		Jobs:              job.NewDispatcher(job.NewMemory(), job.NewMemoryStore(), 1, 1),
+	}
+	svcCtx.Jobs.Handle(JobGenerate, HandleGenerate(svcCtx))
+	svcCtx.Jobs.Start()
+	t.Cleanup(svcCtx.Jobs.Stop)
+	return svcCtx
+}
+
+// waitCharacter 等待角色满足 cond，超时则测试失败
+func waitCharacter(t *testing.T, characters *fakeCharacterModel, id int64, what string, cond func(c model.Character) bool) model.Character {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		c := characters.find(id)
+		if cond(c) {
+			return c
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s: %+v", what, c)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewCharacterGeneratesWithFakeLLM(t *testing.T) {
+	characters := &fakeCharacterModel{characters: make(map[int64]*model.Character)}
+	tags := &fakeCharacterTagModel{}
+	svcCtx := newTestServiceContext(t, characters, tags)
+
+	ctx := context.WithValue(context.Background(), ctxdata.CtxKeyJwtUserId, int64(1))
+	resp, err := NewNewCharacterLogic(ctx, svcCtx).NewCharacter(&types.NewCharacterRequest{
+		Name:        "艾拉",
+		Description: "喜欢下雨天的旅行者",
+		Background:  "在雨季的港口长大",
+		Tags:        []int64{3, 5},
+		// 私有角色同样由后台任务生成
+		IsPublic: false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := resp.Character.Id
+	if len(tags.tags) != 2 || tags.tags[0].CharacterId != id {
+		t.Fatalf("unexpected tags: %+v", tags.tags)
+	}
+
+	// 生成在后台执行，完成后写入人设与初始记忆
+	var character model.Character
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if character = characters.find(id); character.SystemPrompt != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("character not generated: %+v", character)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if character.UserId != 1 || len(character.Personality) != 2 || len(character.InitialMemory) != 1 {
+		t.Fatalf("generated data missing: %+v", character)
+	}
+
+	// 初始记忆作为角色的共享记忆写入向量库，任何用户都能检索到
+	vector, _ := svcCtx.Embedding.GetEmbedding(character.InitialMemory[0])
+	for {
+		memories, err := svcCtx.Embedding.Search(globalkey.Collection(id), vector, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(memories) == 1 && memories[0].Text == character.InitialMemory[0] && memories[0].UserId == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("initial memory not indexed: %+v", memories)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 生成状态从 pending 变为 ready
+	if resp.Character.Status != model.CharacterStatusPending {
+		t.Fatalf("status = %d, want pending", resp.Character.Status)
+	}
+	for characters.find(id).Status != model.CharacterStatusReady {
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %d, want ready", characters.find(id).Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,47 @@
+package character
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type NewLorebookEntryLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewNewLorebookEntryLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NewLorebookEntryLogic {
+	return &NewLorebookEntryLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *NewLorebookEntryLogic) NewLorebookEntry(req *types.NewLorebookEntryRequest) (resp *types.NewLorebookEntryResponse, err error) {
+	if _, err = findOwnedCharacter(l.ctx, l.svcCtx, req.CharacterId); err != nil {
+		return nil, err
+	}
+	entry := &model.LorebookEntry{
+		CharacterId: req.CharacterId,
+		Keywords:    req.Keywords,
+		Content:     req.Content,
+		Priority:    req.Priority,
+		Position:    req.Position,
+		Enabled:     castBool(req.Enabled),
+	}
+	if err = l.svcCtx.LorebookModel.Insert(l.ctx, nil, entry); err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.CharacterId, err)
+	}
+	return &types.NewLorebookEntryResponse{
+		Entry: castLorebookEntry(entry),
+	}, nil
+}
@@ -0,0 +1,56 @@
+package character
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+	"time"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// generateTimeout 超过该时间仍未生成完成视为中断（例如服务重启），允许重新生成
+	generateTimeout = 10 * time.Minute
+)
+
+type RegenerateCharacterLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRegenerateCharacterLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RegenerateCharacterLogic {
+	return &RegenerateCharacterLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// RegenerateCharacter 重新生成失败或中断的角色，只生成缺少的部分，仅角色创建者可操作
+func (l *RegenerateCharacterLogic) RegenerateCharacter(req *types.RegenerateCharacterRequest) error {
+	character, err := findOwnedCharacter(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	switch character.Status {
+	case model.CharacterStatusFailed:
+	case model.CharacterStatusPending, model.CharacterStatusGenerating:
+		if time.Since(character.UpdatedAt) < generateTimeout {
+			return errors.Wrapf(errorz.NewErrCode(errorz.CHARACTER_GENERATING_ERROR), "characterId: %d, status: %d", character.Id, character.Status)
+		}
+	default:
+		return errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "characterId: %d is ready", character.Id)
+	}
+	if err = l.svcCtx.CharacterModel.UpdateStatus(l.ctx, character.Id, model.CharacterStatusPending, ""); err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	// 异步生成并更新
+	startGenerate(l.ctx, l.svcCtx, character)
+	return nil
+}
@@ -0,0 +1,126 @@
+package character
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type UpdateCharacterLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUpdateCharacterLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UpdateCharacterLogic {
+	return &UpdateCharacterLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// UpdateCharacter 修改角色，仅角色创建者可修改。名称、介绍或背景变化时重新生成性格与系统提示词，
+// 背景变化时还会重新生成初始记忆并重建角色的共享记忆
+func (l *UpdateCharacterLogic) UpdateCharacter(req *types.UpdateCharacterRequest) (resp *types.UpdateCharacterResponse, err error) {
+	if err = checkVoice(req.Voice); err != nil {
+		return nil, err
+	}
+	character, err := findOwnedCharacter(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	backgroundChanged := character.Background != req.Background
+	// 系统提示词中包含角色名称，改名同样需要重新生成
+	regenerate := character.Name != req.Name || character.Description != req.Description || backgroundChanged
+	character.Name = req.Name
+	character.Description = req.Description
+	character.Background = req.Background
+	character.OpenLine = req.OpenLine
+	character.AvatarUrl = req.Avatar
+	character.Voice = req.Voice
+	character.TTSConfig = castModelTTSConfig(req.TTSConfig)
+	character.IsPublic = castBool(req.IsPublic)
+	// 只写入本次修改的列，不覆盖生成任务同时写入的结果
+	columns := map[string]interface{}{
+		"name":        character.Name,
+		"description": character.Description,
+		"background":  character.Background,
+		"open_line":   character.OpenLine,
+		"avatar_url":  character.AvatarUrl,
+		"voice":       character.Voice,
+		"tts_config":  character.TTSConfig,
+		"is_public":   character.IsPublic,
+	}
+	if regenerate {
+		// 生成版本加 1，正在执行的生成任务据此发现修改并重新生成
+		character.Personality = nil
+		character.SystemPrompt = ""
+		character.Status, character.StatusReason = model.CharacterStatusPending, ""
+		character.Generation++
+		columns["personality"] = character.Personality
+		columns["system_prompt"] = character.SystemPrompt
+		columns["status"] = character.Status
+		columns["status_reason"] = character.StatusReason
+		columns["generation"] = gorm.Expr("generation + 1")
+		if backgroundChanged {
+			character.InitialMemory = nil
+			columns["initial_memory"] = character.InitialMemory
+		}
+	}
+
+	characterTags, err := l.svcCtx.CharacterTagModel.FindByCharacter(l.ctx, character.Id)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	err = l.svcCtx.CharacterModel.Transaction(l.ctx, func(db *gorm.DB) error {
+		if e := l.svcCtx.CharacterModel.UpdateColumns(l.ctx, db, character.Id, columns); e != nil {
+			return e
+		}
+		// 只增删有变化的标签，(tag_id, character_id) 唯一
+		keep := make(map[int64]bool)
+		for _, tagId := range req.Tags {
+			keep[tagId] = true
+		}
+		for _, ct := range characterTags {
+			if keep[ct.TagId] {
+				delete(keep, ct.TagId)
+				continue
+			}
+			if e := l.svcCtx.CharacterTagModel.Delete(l.ctx, db, ct.Id); e != nil {
+				return e
+			}
+		}
+		added := make([]model.CharacterTag, 0)
+		for _, tagId := range req.Tags {
+			if keep[tagId] {
+				delete(keep, tagId)
+				added = append(added, model.CharacterTag{
+					CharacterId: character.Id,
+					TagId:       tagId,
+				})
+			}
+		}
+		if len(added) == 0 {
+			return nil
+		}
+		return l.svcCtx.CharacterTagModel.Inserts(l.ctx, db, &added)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	if regenerate {
+		// 异步生成并更新
+		startGenerate(l.ctx, l.svcCtx, character)
+	}
+	return &types.UpdateCharacterResponse{
+		Character: castCharacter(character),
+	}, nil
+}
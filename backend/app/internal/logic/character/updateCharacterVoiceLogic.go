@@ -0,0 +1,68 @@
+package character
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type UpdateCharacterVoiceLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUpdateCharacterVoiceLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UpdateCharacterVoiceLogic {
+	return &UpdateCharacterVoiceLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// UpdateCharacterVoice 修改角色的音色与语音合成参数，仅角色创建者可修改
+func (l *UpdateCharacterVoiceLogic) UpdateCharacterVoice(req *types.UpdateCharacterVoiceRequest) (resp *types.UpdateCharacterVoiceResponse, err error) {
+	if err = checkVoice(req.Voice); err != nil {
+		return nil, err
+	}
+	character, err := findOwnedCharacter(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	character.Voice = req.Voice
+	character.TTSConfig = castModelTTSConfig(req.TTSConfig)
+	err = l.svcCtx.CharacterModel.UpdateColumns(l.ctx, nil, character.Id, map[string]interface{}{
+		"voice":      character.Voice,
+		"tts_config": character.TTSConfig,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", req.Id, err)
+	}
+	return &types.UpdateCharacterVoiceResponse{
+		Character: castCharacter(character),
+	}, nil
+}
+
+// findOwnedCharacter 查找当前用户创建的角色
+func findOwnedCharacter(ctx context.Context, svcCtx *svc.ServiceContext, id int64) (*model.Character, error) {
+	userId := ctxdata.GetUidFromCtx(ctx)
+	character, err := svcCtx.CharacterModel.FindOneByUser(ctx, id, userId)
+	if errors.Is(err, model.ErrNotFound) {
+		// 角色不存在或是他人的私有角色
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.CHARACTER_NOT_FOUND_ERROR), "userId: %d, characterId: %d", userId, id)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "characterId: %v,err: %+v", id, err)
+	}
+	if character.UserId != userId {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REQUEST_ROLE_ERROR), "userId: %d, characterId: %d", userId, character.Id)
+	}
+	return character, nil
+}
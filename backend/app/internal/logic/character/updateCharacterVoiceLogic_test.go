@@ -0,0 +1,40 @@
+package character
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+)
+
+func TestFindOwnedCharacterErrors(t *testing.T) {
+	characters := &fakeCharacterModel{characters: map[int64]*model.Character{
+		1: {Id: 1, UserId: 2, IsPublic: 1},
+		2: {Id: 2, UserId: 2},
+	}}
+	svcCtx := newTestServiceContext(t, characters, &fakeCharacterTagModel{})
+	ctx := context.WithValue(context.Background(), ctxdata.CtxKeyJwtUserId, int64(1))
+
+	tests := []struct {
+		name string
+		id   int64
+		want uint32
+	}{
+		{"public character of another user", 1, errorz.REQUEST_ROLE_ERROR},
+		// 他人的私有角色与不存在的角色一样不可见
+		{"private character of another user", 2, errorz.CHARACTER_NOT_FOUND_ERROR},
+		{"missing character", 3, errorz.CHARACTER_NOT_FOUND_ERROR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := findOwnedCharacter(ctx, svcCtx, tt.id)
+			var codeErr *errorz.CodeError
+			if !errors.As(err, &codeErr) || codeErr.GetErrCode() != tt.want {
+				t.Fatalf("err = %v, want code %d", err, tt.want)
+			}
+		})
+	}
+}
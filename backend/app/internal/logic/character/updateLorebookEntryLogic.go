@@ -0,0 +1,44 @@
+package character
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type UpdateLorebookEntryLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUpdateLorebookEntryLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UpdateLorebookEntryLogic {
+	return &UpdateLorebookEntryLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *UpdateLorebookEntryLogic) UpdateLorebookEntry(req *types.UpdateLorebookEntryRequest) (resp *types.UpdateLorebookEntryResponse, err error) {
+	entry, err := findOwnedLorebookEntry(l.ctx, l.svcCtx, req.CharacterId, req.EntryId)
+	if err != nil {
+		return nil, err
+	}
+	entry.Keywords = req.Keywords
+	entry.Content = req.Content
+	entry.Priority = req.Priority
+	entry.Position = req.Position
+	entry.Enabled = castBool(req.Enabled)
+	if err = l.svcCtx.LorebookModel.Update(l.ctx, nil, entry); err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "entryId: %v,err: %+v", req.EntryId, err)
+	}
+	return &types.UpdateLorebookEntryResponse{
+		Entry: castLorebookEntry(entry),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ArchiveSessionLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewArchiveSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ArchiveSessionLogic {
+	return &ArchiveSessionLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ArchiveSession 归档或取消归档会话，已归档的会话默认不出现在会话列表中
+func (l *ArchiveSessionLogic) ArchiveSession(req *types.ArchiveSessionRequest) error {
+	session, err := findOwnedSession(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	err = l.svcCtx.SessionModel.UpdateColumns(l.ctx, nil, session.Id, map[string]interface{}{
+		"is_archived": req.Archived,
+	})
+	if err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "sessionId: %v,err: %+v", session.Id, err)
+	}
+	return nil
+}
@@ -2,23 +2,19 @@ package chat
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
-	"github.com/sashabaranov/go-openai"
 	"github.com/zeromicro/go-zero/core/logx"
 	"gorm.io/gorm"
-	"log"
-	"net/http"
-	"net/url"
+	"io"
 	"qiniuyun/backend/app/internal/svc"
 	"qiniuyun/backend/app/internal/types"
 	"qiniuyun/backend/common/auth"
 	"qiniuyun/backend/common/globalkey"
+	"qiniuyun/backend/common/llm"
 	"qiniuyun/backend/model"
-	"strings"
+	"sync"
 )
 
 const (
@@ -27,34 +23,99 @@ const (
 	WSMessageResponseTypeDone    = "done"
 	WSMessageResponseTypeAudio   = "audio"
 
+	WSMessageResponseTypeTranscript = "transcript"
+	WSMessageResponseTypeHistory    = "history"
+
 	WSMessageRequestTypeText  = "text"
 	WSMessageRequestTypeVoice = "voice"
 	WSMessageRequestTypeAuth  = "auth"
+	WSMessageRequestTypeStop  = "stop"
+
+	WSMessageRequestTypeRegenerate = "regenerate"
+	WSMessageRequestTypeEdit       = "edit"
+
+	WSMessageRequestTypeSpeechStart = "speech_start"
+	WSMessageRequestTypeSpeechEnd   = "speech_end"
+
+	DoneReasonCancelled = "cancelled"
+	DoneReasonError     = "error"
 
 	RoleUser      = "user"
 	RoleAssistant = "assistant"
 	RoleSystem    = "system"
 
-	MaxHistoryMessages = 10
+	maxPendingRequests = 16
+)
+
+var (
+	errStopped      = errors.New("reply stopped by client")
+	errDisconnected = errors.New("client disconnected")
+	errStreamFailed = errors.New("llm stream failed")
 )
 
 type wsResponse struct {
-	Type    string         `json:"type,omitempty"`
-	Msg     *model.Message `json:"msg,omitempty"`
-	Content string         `json:"content,omitempty"`
-	Audio   []byte         `json:"audio,omitempty"`
+	Type     string         `json:"type,omitempty"`
+	Msg      *model.Message `json:"msg,omitempty"`
+	Content  string         `json:"content,omitempty"`
+	Audio    []byte         `json:"audio,omitempty"`
+	Encoding string         `json:"encoding,omitempty"`
+	Seq      int            `json:"seq,omitempty"`
+	Final    bool           `json:"final,omitempty"`
+	Reason   string         `json:"reason,omitempty"`
+	Cursor   int64          `json:"cursor,omitempty"`
+	HasMore  bool           `json:"has_more,omitempty"`
+}
+
+// messageMetadata 存入 Message.Metadata 的附加信息
+type messageMetadata struct {
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+func castMetadata(metadata messageMetadata) string {
+	if metadata == (messageMetadata{}) {
+		return ""
+	}
+	res, _ := json.Marshal(metadata)
+	return string(res)
+}
+
+// replyCanceler 记录当前正在生成的回复，读协程收到 stop 或断开时取消它
+type replyCanceler struct {
+	mu         sync.Mutex
+	cancelFunc context.CancelCauseFunc
+}
+
+func (r *replyCanceler) begin(ctx context.Context) (context.Context, func()) {
+	replyCtx, cancel := context.WithCancelCause(ctx)
+	r.mu.Lock()
+	r.cancelFunc = cancel
+	r.mu.Unlock()
+	return replyCtx, func() {
+		r.mu.Lock()
+		r.cancelFunc = nil
+		r.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+func (r *replyCanceler) cancel(cause error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancelFunc != nil {
+		r.cancelFunc(cause)
+	}
 }
 
 type ChatLogic struct {
 	logx.Logger
 	ctx    context.Context
 	svcCtx *svc.ServiceContext
+	// replies 本连接内已开始的语音回复数，用作二进制音频帧中的消息编号
+	replies uint32
+	// userId 已通过鉴权的用户
+	userId int64
 }
 
-var (
-	ttsUrl = url.URL{Scheme: "wss", Host: "openai.qiniu.com", Path: "/v1/voice/tts"}
-)
-
 func NewChatLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ChatLogic {
 	return &ChatLogic{
 		Logger: logx.WithContext(ctx),
@@ -63,7 +124,8 @@ func NewChatLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ChatLogic {
 	}
 }
 
-func (l *ChatLogic) Chat(req *types.ChatRequest, conn *websocket.Conn) error {
+func (l *ChatLogic) Chat(req *types.ChatRequest, ws *websocket.Conn) error {
+	conn := &wsConn{Conn: ws}
 	ctx := context.Background()
 	sessionId := req.SessionId
 	var authReq auth.WSRequest
@@ -85,11 +147,14 @@ func (l *ChatLogic) Chat(req *types.ChatRequest, conn *websocket.Conn) error {
 	if session.UserId != userId {
 		return errors.New("no permission")
 	}
-	character, err := l.svcCtx.CharacterModel.FindOne(ctx, session.CharacterId)
+	l.userId = userId
+	// 角色被创建者设为私有后，其他用户不能继续在已有会话中对话
+	character, err := l.svcCtx.CharacterModel.FindOneByUser(ctx, session.CharacterId, userId)
 	if err != nil {
 		return err
 	}
-	historyMsgs, err := l.svcCtx.MessageModel.FindBySession(ctx, sessionId)
+	// 只推送最新的一页消息，之后以 history 消息告知游标，更早的消息通过 GET /api/session/:id/messages?before=<cursor> 获取
+	historyMsgs, hasMore, err := findMessagePage(ctx, l.svcCtx, sessionId, 0, 0, messagePageSize)
 	if err != nil {
 		return err
 	}
@@ -99,209 +164,276 @@ func (l *ChatLogic) Chat(req *types.ChatRequest, conn *websocket.Conn) error {
 			Msg:  msg,
 		})
 	}
-	for {
-		_, content, err := conn.ReadMessage()
-		if err != nil {
-			log.Println("read:", err)
-			break
+	history := wsResponse{Type: WSMessageResponseTypeHistory, HasMore: hasMore}
+	if len(historyMsgs) > 0 {
+		history.Cursor = historyMsgs[0].Id
+	}
+	conn.WriteJSON(history)
+	canceler := &replyCanceler{}
+	for data := range l.readRequests(conn, canceler) {
+		// 角色仍在生成时每次回复前重新读取，生成完成后即可使用生成的系统提示词
+		if character.Status != model.CharacterStatusReady {
+			if c, e := l.svcCtx.CharacterModel.FindOneByUser(ctx, character.Id, userId); e == nil {
+				character = c
+			}
 		}
-		var data auth.WSRequest
-		json.Unmarshal(content, &data)
-
-		historyMsgs = append(historyMsgs, &model.Message{
-			SessionId: sessionId,
-			Role:      RoleUser,
-			Content:   string(content),
-		})
-		vector, err := l.svcCtx.Embedding.GetEmbedding(string(content))
-		var memory []string
-		if err == nil {
-			memory, _ = l.svcCtx.Embedding.Search(globalkey.Collection(character.Id), vector)
+		replyCtx, done := canceler.begin(ctx)
+		switch data.Type {
+		case WSMessageRequestTypeText, WSMessageRequestTypeVoice:
+			err = l.reply(replyCtx, conn, data, sessionId, character)
+		case WSMessageRequestTypeEdit:
+			err = l.edit(replyCtx, conn, data, sessionId, character)
+		case WSMessageRequestTypeRegenerate:
+			err = l.regenerate(replyCtx, conn, sessionId, character)
 		}
-		stream, err := l.svcCtx.LLM.GetStream(castHistory(historyMsgs, character.SystemPrompt, memory))
+		done()
 		if err != nil {
 			return err
 		}
-		var fullReply string
-		for {
-			resp, err := stream.Recv()
-			if err != nil {
-				break
-			}
-			if len(resp.Choices) == 0 {
-				continue
-			}
-			delta := resp.Choices[0].Delta.Content
-			if delta == "" {
-				continue
-			}
-			fullReply += delta
-			if data.Type == WSMessageRequestTypeText {
-				conn.WriteJSON(wsResponse{
-					Type:    WSMessageResponseTypeDelta,
-					Content: delta,
-				})
-			}
+		l.extractMemories(sessionId)
+		l.summarize(sessionId)
+		l.generateTitle(sessionId)
+	}
+	return nil
+}
+
+// reply 在当前分支末尾追加用户的新消息并生成回复
+func (l *ChatLogic) reply(ctx context.Context, conn *wsConn, data auth.WSRequest, sessionId int64, character *model.Character) error {
+	path, err := l.svcCtx.MessageModel.FindPage(context.Background(), sessionId, 0, 0, historyWindow)
+	if err != nil {
+		return err
+	}
+	userMsg := &model.Message{
+		SessionId:  sessionId,
+		Role:       RoleUser,
+		Content:    data.Content,
+		IsSelected: 1,
+	}
+	if len(path) > 0 {
+		userMsg.ParentId = path[len(path)-1].Id
+	}
+	return l.answer(ctx, conn, data.Type, character, path, userMsg, false)
+}
+
+// edit 修改当前分支上较早的一条用户消息：新消息作为原消息的兄弟节点，从该处分叉出新的分支
+func (l *ChatLogic) edit(ctx context.Context, conn *wsConn, data auth.WSRequest, sessionId int64, character *model.Character) error {
+	msg, err := l.svcCtx.MessageModel.FindOne(context.Background(), data.MessageId)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		return err
+	}
+	if msg == nil || msg.SessionId != sessionId || msg.Role != RoleUser || msg.IsActive != 1 {
+		logx.Errorf("message %d is not a user message on the active branch of session %d", data.MessageId, sessionId)
+		return nil
+	}
+	path, err := l.svcCtx.MessageModel.FindPage(context.Background(), sessionId, msg.Id, 0, historyWindow)
+	if err != nil {
+		return err
+	}
+	userMsg := &model.Message{
+		SessionId:  sessionId,
+		ParentId:   msg.ParentId,
+		Role:       RoleUser,
+		Content:    data.Content,
+		IsSelected: 1,
+	}
+	return l.answer(ctx, conn, WSMessageRequestTypeText, character, path, userMsg, true)
+}
+
+// answer 基于 path 和新的用户消息生成回复，将两条消息作为新分支持久化。
+// echo 为 true 时在结束前回传持久化后的两条消息，供客户端更新分支
+func (l *ChatLogic) answer(ctx context.Context, conn *wsConn, outputType string, character *model.Character, path []*model.Message, userMsg *model.Message, echo bool) error {
+	historyMsgs := append(path[:len(path):len(path)], userMsg)
+	fullReply, cause, err := l.generate(ctx, conn, outputType, character, historyMsgs, userMsg.Content)
+	if err != nil {
+		return err
+	}
+	assistantMsg := &model.Message{
+		SessionId:  userMsg.SessionId,
+		Role:       RoleAssistant,
+		Content:    fullReply,
+		Metadata:   castMetadata(messageMetadata{Truncated: cause != nil}),
+		IsSelected: 1,
+		// 用户消息被选中后成为当前分支的末尾，回复接在其后
+		IsActive: 1,
+	}
+	if err := l.svcCtx.MessageModel.Transaction(context.Background(), func(db *gorm.DB) error {
+		if err := l.svcCtx.MessageModel.Insert(context.Background(), db, userMsg); err != nil {
+			return err
 		}
-		_ = stream.Close()
-		if data.Type == WSMessageRequestTypeVoice {
-			audioRes(fullReply, character.Voice, l.svcCtx.Config.LLM.ApiKey, conn)
-			if err := conn.WriteJSON(wsResponse{
-				Type: WSMessageResponseTypeMessage,
-				Msg: &model.Message{
-					SessionId: sessionId,
-					Role:      RoleAssistant,
-					Content:   fullReply,
-				},
-			}); err != nil {
-				logx.Error(err)
-			}
+		if err := l.svcCtx.MessageModel.SelectChild(context.Background(), db, userMsg.SessionId, userMsg.ParentId, userMsg.Id); err != nil {
+			return err
 		}
-		historyMsgs = append(historyMsgs, &model.Message{
-			SessionId: sessionId,
-			Role:      RoleAssistant,
-			Content:   fullReply,
-		})
-		if err := l.svcCtx.MessageModel.Transaction(ctx, func(db *gorm.DB) error {
-			if err := l.svcCtx.MessageModel.Insert(ctx, db, &model.Message{
-				SessionId: sessionId,
-				Role:      RoleUser,
-				Content:   string(content),
-			}); err != nil {
-				return err
-			}
-			if err := l.svcCtx.MessageModel.Insert(ctx, db, &model.Message{
-				SessionId: sessionId,
-				Role:      RoleAssistant,
-				Content:   fullReply,
-			}); err != nil {
-				return err
-			}
-			return nil
+		assistantMsg.ParentId = userMsg.Id
+		if err := l.svcCtx.MessageModel.Insert(context.Background(), db, assistantMsg); err != nil {
+			return err
+		}
+		return l.svcCtx.SessionModel.Touch(context.Background(), db, userMsg.SessionId)
+	}); err != nil {
+		logx.Error(err)
+		return err
+	}
+	if echo {
+		if err := conn.WriteJSON(wsResponse{
+			Type: WSMessageResponseTypeMessage,
+			Msg:  userMsg,
 		}); err != nil {
 			logx.Error(err)
-			return err
 		}
-		for i := 0; i < 5; i++ {
-			if err := conn.WriteJSON(wsResponse{
-				Type: WSMessageResponseTypeDone,
-			}); err != nil {
-				logx.Error(err)
-			}
+	}
+	if echo || (outputType == WSMessageRequestTypeVoice && cause == nil) {
+		if err := conn.WriteJSON(wsResponse{
+			Type: WSMessageResponseTypeMessage,
+			Msg:  assistantMsg,
+		}); err != nil {
+			logx.Error(err)
 		}
 	}
+	finish(conn, cause)
 	return nil
 }
 
-func castHistory(messages []*model.Message, systemPrompt string, memory []string) []openai.ChatCompletionMessage {
-	if len(messages) > MaxHistoryMessages {
-		messages = messages[len(messages)-MaxHistoryMessages:]
-	}
-	// 构建 memory 内容，使用清晰分隔符
-	memoryContent := ""
-	if len(memory) > 0 {
-		memoryContent = "=== Relevant Memory ===\n" + strings.Join(memory, "\n") + "\n=== End of Memory ==="
-	}
-	fullSystemPrompt := systemPrompt
-	if memoryContent != "" {
-		fullSystemPrompt += "\n\n" + memoryContent
-	}
-	chatMessages := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
-	chatMessages = append(chatMessages, openai.ChatCompletionMessage{
-		Role:    RoleSystem,
-		Content: fullSystemPrompt,
-	})
-	for _, msg := range messages {
-		chatMessages = append(chatMessages, openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+// regenerate 去掉最后一条 assistant 回复后重新生成，新回复作为其兄弟节点保存并被选中
+func (l *ChatLogic) regenerate(ctx context.Context, conn *wsConn, sessionId int64, character *model.Character) error {
+	path, err := l.svcCtx.MessageModel.FindPage(context.Background(), sessionId, 0, 0, historyWindow)
+	if err != nil {
+		return err
 	}
-	return chatMessages
+	n := len(path)
+	if n < 2 || path[n-1].Role != RoleAssistant || path[n-2].Role != RoleUser {
+		logx.Errorf("session %d has no reply to regenerate", sessionId)
+		return nil
+	}
+	userMsg := path[n-2]
+	fullReply, cause, err := l.generate(ctx, conn, WSMessageRequestTypeText, character, path[:n-1], userMsg.Content)
+	if err != nil {
+		return err
+	}
+	alternative := &model.Message{
+		SessionId:  sessionId,
+		ParentId:   userMsg.Id,
+		Role:       RoleAssistant,
+		Content:    fullReply,
+		Metadata:   castMetadata(messageMetadata{Truncated: cause != nil}),
+		IsSelected: 1,
+	}
+	if err := l.svcCtx.MessageModel.Transaction(context.Background(), func(db *gorm.DB) error {
+		if err := l.svcCtx.MessageModel.Insert(context.Background(), db, alternative); err != nil {
+			return err
+		}
+		if err := l.svcCtx.MessageModel.SelectChild(context.Background(), db, sessionId, userMsg.Id, alternative.Id); err != nil {
+			return err
+		}
+		return l.svcCtx.SessionModel.Touch(context.Background(), db, sessionId)
+	}); err != nil {
+		logx.Error(err)
+		return err
+	}
+	if err := conn.WriteJSON(wsResponse{
+		Type: WSMessageResponseTypeMessage,
+		Msg:  alternative,
+	}); err != nil {
+		logx.Error(err)
+	}
+	finish(conn, cause)
+	return nil
 }
 
-func audioRes(text, voiceType, sk string, conn *websocket.Conn) {
-	input := setupInput(voiceType, "mp3", 1.0, text)
-	c, _, err := websocket.DefaultDialer.Dial(ttsUrl.String(), http.Header{
-		"Authorization": []string{fmt.Sprintf("Bearer %s", sk)},
-		"VoiceType":     []string{voiceType},
-	})
-	if err != nil {
-		fmt.Println("dial err:", err)
-		return
+// generate 检索记忆并流式生成回复，text 模式逐段推送 delta，voice 模式逐句合成语音并按序推送。
+// 回复提前结束时返回已生成的部分，cause 为提前结束的原因：stop、断开或 LLM 流中途出错
+func (l *ChatLogic) generate(ctx context.Context, conn *wsConn, outputType string, character *model.Character, historyMsgs []*model.Message, query string) (reply string, cause error, err error) {
+	vector, err := l.svcCtx.Embedding.GetEmbedding(query)
+	var memory, userMemory []string
+	if err == nil {
+		memories, _ := l.svcCtx.Embedding.Search(globalkey.Collection(character.Id), vector, l.userId)
+		for _, m := range memories {
+			if m.UserId == 0 {
+				memory = append(memory, m.Text)
+			} else {
+				userMemory = append(userMemory, m.Text)
+			}
+		}
 	}
-	defer c.Close()
-	err = c.WriteMessage(websocket.BinaryMessage, input)
+	var lore []*model.LorebookEntry
+	if entries, err := l.svcCtx.LorebookModel.FindByCharacter(context.Background(), character.Id); err == nil {
+		lore = matchLorebook(entries, historyMsgs)
+	}
+	var summary string
+	var user persona
+	// 已并入摘要的消息不再作为历史发送
+	recent := historyMsgs
+	session, err := l.svcCtx.SessionModel.FindOne(context.Background(), historyMsgs[len(historyMsgs)-1].SessionId)
+	if err == nil {
+		summary, recent = storySoFar(context.Background(), l.svcCtx, session, historyMsgs)
+		user = resolvePersona(context.Background(), l.svcCtx, session)
+	}
+	stream, err := l.svcCtx.LLM.GetStream(ctx, newContextBuilder(l.svcCtx.Config.LLM).build(promptParts{
+		systemPrompt:    characterPrompt(character),
+		exampleDialogue: character.ExampleDialogue,
+		summary:         summary,
+		lore:            lore,
+		memory:          memory,
+		userMemory:      userMemory,
+		persona:         user,
+		placeholders:    llm.Placeholders{User: user.name, Char: character.Name},
+	}, recent))
 	if err != nil {
-		fmt.Println("write message fail, err:", err.Error())
-		return
+		// 流建立之前就收到 stop 或断开时按取消处理，由调用方保存空的截断回复
+		if cause = context.Cause(ctx); cause != nil {
+			return "", cause, nil
+		}
+		return "", nil, err
 	}
-	count := 0
-	var audio []byte
+	var tts *ttsStream
+	if outputType == WSMessageRequestTypeVoice {
+		l.replies++
+		tts = newTTSStream(ctx, conn, l.svcCtx.TTS, l.replies, ttsParams(character))
+	}
+	var fullReply string
+	// streamErr 记录流式生成中途的失败，已生成的部分按截断保存
+	var streamErr error
 	for {
-		count++
-		var message []byte
-		_, message, err := c.ReadMessage()
+		delta, err := stream.Recv()
 		if err != nil {
-			fmt.Println("read message fail, err:", err.Error())
+			if !errors.Is(err, io.EOF) {
+				streamErr = err
+			}
 			break
 		}
-		var resp RelayTTSResponse
-		err = json.Unmarshal(message, &resp)
-		if err != nil {
-			fmt.Println("unmarshal fail, err:", err.Error())
+		fullReply += delta
+		if tts != nil {
+			tts.Write(delta)
 			continue
 		}
-		d, err := base64.StdEncoding.DecodeString(resp.Data)
-		if err != nil {
-			fmt.Println("decode fail, err:", err.Error())
-		}
-		audio = append(audio, d...)
-		if resp.Sequence < 0 {
-			fmt.Println("write to client")
-			conn.WriteJSON(wsResponse{
-				Type:  WSMessageResponseTypeAudio,
-				Audio: audio,
-			})
-			break
-		}
+		conn.WriteJSON(wsResponse{
+			Type:    WSMessageResponseTypeDelta,
+			Content: delta,
+		})
 	}
+	_ = stream.Close()
+	if tts != nil {
+		tts.Close()
+	}
+	if cause = context.Cause(ctx); cause == nil && streamErr != nil {
+		logx.Errorf("recv stream: %+v", streamErr)
+		cause = errors.Wrap(errStreamFailed, streamErr.Error())
+	}
+	return fullReply, cause, nil
 }
 
-func setupInput(voiceType string, encoding string, speedRatio float64, text string) []byte {
-	params := &TTSRequest{
-		Audio: Audio{
-			VoiceType:  voiceType,
-			Encoding:   encoding,
-			SpeedRatio: speedRatio,
-		},
-		Request: Request{
-			Text: text,
-		},
-	}
-	resStr, _ := json.Marshal(params)
-	return resStr
-}
-
-type TTSRequest struct {
-	Audio   `json:"audio"`
-	Request `json:"request"`
-}
-type Audio struct {
-	VoiceType  string  `json:"voice_type"`
-	Encoding   string  `json:"encoding"`
-	SpeedRatio float64 `json:"speed_ratio"`
-}
-type Request struct {
-	Text string `json:"text"`
-}
-type RelayTTSResponse struct {
-	Reqid     string    `json:"reqid"`
-	Operation string    `json:"operation"`
-	Sequence  int       `json:"sequence"`
-	Data      string    `json:"data"`
-	Addition  *Addition `json:"addition,omitempty"`
-}
-type Addition struct {
-	Duration string `json:"duration"`
+// finish 通知客户端本轮回复结束，提前结束时附带原因；客户端已断开则不再发送
+func finish(conn *wsConn, cause error) {
+	if errors.Is(cause, errDisconnected) {
+		return
+	}
+	done := wsResponse{Type: WSMessageResponseTypeDone}
+	if errors.Is(cause, errStreamFailed) {
+		done.Reason = DoneReasonError
+	} else if cause != nil {
+		done.Reason = DoneReasonCancelled
+	}
+	for i := 0; i < 5; i++ {
+		if err := conn.WriteJSON(done); err != nil {
+			logx.Error(err)
+		}
+	}
 }
@@ -0,0 +1,505 @@
+package chat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/auth"
+	"qiniuyun/backend/common/embedding"
+	"qiniuyun/backend/common/globalkey"
+	"qiniuyun/backend/model"
+)
+
+type testResponse struct {
+	Type    string         `json:"type"`
+	Msg     *model.Message `json:"msg"`
+	Content string         `json:"content"`
+	Reason  string         `json:"reason"`
+}
+
+func TestMain(m *testing.M) {
+	auth.Secret("test-secret")
+	os.Exit(m.Run())
+}
+
+// dialChat 启动处理 sessionId 对话的 WebSocket 服务，以 userId 连接并完成鉴权
+func dialChat(t *testing.T, env *testEnv, sessionId, userId int64) *websocket.Conn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		_ = NewChatLogic(r.Context(), env.svcCtx).Chat(&types.ChatRequest{SessionId: sessionId}, ws)
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	token, _, err := auth.GetJwtToken(3600, userId, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.WriteJSON(auth.WSRequest{Type: WSMessageRequestTypeAuth, Token: token}); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// readUntil 读取服务端消息直到出现 typ 类型的消息，返回期间收到的全部消息
+func readUntil(t *testing.T, conn *websocket.Conn, typ string) []testResponse {
+	t.Helper()
+	var res []testResponse
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		var resp testResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatalf("read %s: %v", typ, err)
+		}
+		res = append(res, resp)
+		if resp.Type == typ {
+			return res
+		}
+	}
+}
+
+func send(t *testing.T, conn *websocket.Conn, typ, content string) {
+	t.Helper()
+	if err := conn.WriteJSON(auth.WSRequest{Type: typ, Content: content}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitRequest 等待 fakeLLM 收到一次对话请求
+func waitRequest(t *testing.T, env *testEnv) {
+	t.Helper()
+	select {
+	case <-env.llm.requests:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no request to the LLM")
+	}
+}
+
+// readDone 读取一轮回复直到 done，并跳过服务端重复发送的 done
+func readDone(t *testing.T, conn *websocket.Conn) []testResponse {
+	t.Helper()
+	res := readUntil(t, conn, WSMessageResponseTypeDone)
+	for i := 1; i < 5; i++ {
+		readUntil(t, conn, WSMessageResponseTypeDone)
+	}
+	return res
+}
+
+// streamed 拼接 responses 中的增量内容，返回拼接结果与 done 消息
+func streamed(responses []testResponse) (string, testResponse) {
+	var sb strings.Builder
+	for _, resp := range responses {
+		if resp.Type == WSMessageResponseTypeDelta {
+			sb.WriteString(resp.Content)
+		}
+	}
+	return sb.String(), responses[len(responses)-1]
+}
+
+// lastReply 返回最后保存的一条回复
+func lastReply(t *testing.T, env *testEnv) model.Message {
+	t.Helper()
+	messages := env.messages.all()
+	if len(messages) == 0 || messages[len(messages)-1].Role != RoleAssistant {
+		t.Fatalf("no reply saved: %+v", messages)
+	}
+	return messages[len(messages)-1]
+}
+
+func TestChatReply(t *testing.T) {
+	env := newTestEnv(t, fakeReply{chunks: []string{"旅行者，", "今天也下雨了呢。"}})
+	conn := dialChat(t, env, 1, 1)
+
+	send(t, conn, WSMessageRequestTypeText, "你好")
+	content, done := streamed(readDone(t, conn))
+	if content != "旅行者，今天也下雨了呢。" || done.Reason != "" {
+		t.Fatalf("streamed %q, done %+v", content, done)
+	}
+	messages := env.messages.all()
+	if len(messages) != 2 || messages[0].Role != RoleUser || messages[0].Content != "你好" {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+	if reply := messages[1]; reply.Content != content || reply.Metadata != "" {
+		t.Fatalf("unexpected reply: %+v", reply)
+	}
+}
+
+func TestChatReplyRetrievesMemory(t *testing.T) {
+	env := newTestEnv(t)
+	const memory = "艾拉最喜欢下雨天的港口"
+	vector, _ := env.svcCtx.Embedding.GetEmbedding(memory)
+	if err := env.svcCtx.Embedding.InsertVectors(context.Background(), globalkey.Collection(10), []string{memory}, [][]float32{vector}, embedding.Payload{Source: embedding.SourceCharacter}); err != nil {
+		t.Fatal(err)
+	}
+	conn := dialChat(t, env, 1, 1)
+
+	send(t, conn, WSMessageRequestTypeText, "艾拉最喜欢下雨天的港口吗")
+	readDone(t, conn)
+	// 与问题相关的记忆附加在系统提示词中
+	if system := env.llm.lastMessages()[0]; system.Role != RoleSystem || !strings.Contains(system.Content, memory) {
+		t.Fatalf("memory not in the system prompt: %+v", system)
+	}
+}
+
+func TestChatReplyInjectsLorebook(t *testing.T) {
+	env := newTestEnv(t)
+	env.lorebook.entries = []*model.LorebookEntry{
+		{CharacterId: 10, Keywords: model.StringArray{"灯塔"}, Content: "灯塔在十年前的风暴后废弃。", Position: LorePositionAfter, Enabled: 1},
+		{CharacterId: 10, Keywords: model.StringArray{"集市"}, Content: "集市每周六开放。", Position: LorePositionAfter, Enabled: 1},
+	}
+	conn := dialChat(t, env, 1, 1)
+
+	// 只注入被最近消息触发的条目
+	send(t, conn, WSMessageRequestTypeText, "你去过灯塔吗")
+	readDone(t, conn)
+	system := env.llm.lastMessages()[0].Content
+	if !strings.Contains(system, "灯塔在十年前的风暴后废弃。") || strings.Contains(system, "集市每周六开放。") {
+		t.Fatalf("unexpected lore in the system prompt: %q", system)
+	}
+}
+
+func TestChatExtractsUserMemories(t *testing.T) {
+	env := newTestEnv(t)
+	conn := dialChat(t, env, 1, 1)
+
+	// 当前分支上累计足够多的消息后在后台提取关于用户的记忆
+	for i := 0; i < memoryExtractInterval/2; i++ {
+		send(t, conn, WSMessageRequestTypeText, "我喜欢下雨天")
+		readDone(t, conn)
+	}
+	messages := env.messages.all()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		session, _ := env.sessions.FindOne(context.Background(), 1)
+		if session.MemoryCursor == messages[len(messages)-1].Id {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("memory cursor = %d, want %d", session.MemoryCursor, messages[len(messages)-1].Id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	const memory = "这是一段用于测试的记忆。"
+	vector, _ := env.svcCtx.Embedding.GetEmbedding(memory)
+	// 提取的记忆只属于该用户
+	if memories, _ := env.svcCtx.Embedding.Search(globalkey.Collection(10), vector, 1); len(memories) != 1 || memories[0].Text != memory || memories[0].UserId != 1 || memories[0].SessionId != 1 {
+		t.Fatalf("user memories = %+v", memories)
+	}
+	if memories, _ := env.svcCtx.Embedding.Search(globalkey.Collection(10), vector, 2); len(memories) != 0 {
+		t.Fatalf("user 2 sees the memories of user 1: %+v", memories)
+	}
+}
+
+func TestExtractMemoriesReadsBatchAfterCursor(t *testing.T) {
+	env := newTestEnv(t)
+	ids := insertChain(env, memoryExtractBatch+20)
+	env.sessions.sessions[1].MemoryCursor = ids[9]
+
+	// 每次只提取游标之后的一批消息，其余留给之后的任务
+	for _, want := range []int64{ids[9+memoryExtractBatch], ids[len(ids)-1]} {
+		if err := extractSessionMemories(context.Background(), env.svcCtx, 1); err != nil {
+			t.Fatal(err)
+		}
+		if session, _ := env.sessions.FindOne(context.Background(), 1); session.MemoryCursor != want {
+			t.Fatalf("memory cursor = %d, want %d", session.MemoryCursor, want)
+		}
+	}
+}
+
+func TestChatGeneratesTitle(t *testing.T) {
+	env := newTestEnv(t)
+	conn := dialChat(t, env, 1, 1)
+
+	// 用户发送足够多的消息后根据对话生成标题
+	for i := 0; i < titleExchanges; i++ {
+		send(t, conn, WSMessageRequestTypeText, "我们去港口吧")
+		readDone(t, conn)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		session, _ := env.sessions.FindOne(context.Background(), 1)
+		if session.Title == "测试对话" && session.TitleSource == model.SessionTitleGenerated {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("title not generated: %+v", session)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 用户修改过的标题不再被覆盖
+	if err := NewUpdateSessionLogic(userContext(1), env.svcCtx).UpdateSession(&types.UpdateSessionRequest{Id: 1, Title: " 港口之旅 "}); err != nil {
+		t.Fatal(err)
+	}
+	if err := generateSessionTitle(context.Background(), env.svcCtx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if session, _ := env.sessions.FindOne(context.Background(), 1); session.Title != "港口之旅" || session.TitleSource != model.SessionTitleManual {
+		t.Fatalf("manual title overwritten: %+v", session)
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := map[string]string{
+		"  「雨中的港口」。 ":                         "雨中的港口",
+		"\"初次相遇\"":                            "初次相遇",
+		strings.Repeat("长", maxTitleLength+5): strings.Repeat("长", maxTitleLength),
+	}
+	for in, want := range tests {
+		if got := cleanTitle(in); got != want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestChatSummarizesOlderMessages(t *testing.T) {
+	env := newTestEnv(t)
+	conn := dialChat(t, env, 1, 1)
+
+	// 放不进上下文预算的消息足够多时在后台更新剧情摘要
+	for i := 0; i < 15; i++ {
+		send(t, conn, WSMessageRequestTypeText, "我们继续走吧")
+		readDone(t, conn)
+	}
+	const summary = "这是一段用于测试的剧情摘要。"
+	var session *model.Session
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		session, _ = env.sessions.FindOne(context.Background(), 1)
+		if session.Summary == summary && session.SummaryCursor != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session not summarized: %+v", session)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 之后的回复带上剧情摘要，摘要覆盖的消息不再出现在上下文中
+	send(t, conn, WSMessageRequestTypeText, "到了吗")
+	readDone(t, conn)
+	context := env.llm.lastMessages()
+	if !strings.Contains(context[0].Content, summary) {
+		t.Fatalf("summary not in the system prompt: %+v", context[0])
+	}
+	// 消息 ID 在当前分支上连续递增，摘要之后的消息为 ID 大于 SummaryCursor 的部分
+	messages := env.messages.all()
+	if after := messages[len(messages)-1].Id - session.SummaryCursor; int64(len(context)-1) > after {
+		t.Fatalf("context has %d messages, only %d after the summary", len(context)-1, after)
+	}
+}
+
+func TestSummarizeReadsBatchAfterCursor(t *testing.T) {
+	env := newTestEnv(t)
+	ids := insertChain(env, historyWindow+summaryBatch+20)
+	// 摘要覆盖到的消息已不在当前分支上时从分支开头重新生成，每次只并入一批消息
+	stale := &model.Message{SessionId: 1, Role: RoleUser, Content: "你好"}
+	_ = env.messages.Insert(context.Background(), nil, stale)
+	env.sessions.sessions[1].Summary, env.sessions.sessions[1].SummaryCursor = "旧的摘要", stale.Id
+
+	if err := summarizeSession(context.Background(), env.svcCtx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if session, _ := env.sessions.FindOne(context.Background(), 1); session.SummaryCursor != ids[summaryBatch-1] {
+		t.Fatalf("summary cursor = %d, want %d", session.SummaryCursor, ids[summaryBatch-1])
+	}
+	// 再次执行时从游标处继续，不超过仍在上下文中的消息
+	if err := summarizeSession(context.Background(), env.svcCtx, 1); err != nil {
+		t.Fatal(err)
+	}
+	session, _ := env.sessions.FindOne(context.Background(), 1)
+	if session.SummaryCursor <= ids[summaryBatch-1] || session.SummaryCursor >= ids[len(ids)-1] {
+		t.Fatalf("summary cursor = %d after the second batch", session.SummaryCursor)
+	}
+}
+
+func TestChatReplyReadsRecentHistoryWindow(t *testing.T) {
+	env := newTestEnv(t)
+	ids := insertChain(env, historyWindow+20)
+	// 摘要覆盖到的消息早于最近的 historyWindow 条消息，但仍在当前分支上
+	const summary = "旅行者与艾拉在港口相识。"
+	env.sessions.sessions[1].Summary, env.sessions.sessions[1].SummaryCursor = summary, ids[5]
+	conn := dialChat(t, env, 1, 1)
+
+	send(t, conn, WSMessageRequestTypeText, "到了吗")
+	readDone(t, conn)
+	context := env.llm.lastMessages()
+	if !strings.Contains(context[0].Content, summary) {
+		t.Fatalf("summary not in the system prompt: %+v", context[0])
+	}
+	if n := len(context) - 1; n > historyWindow+1 {
+		t.Fatalf("context has %d messages, want at most %d", n, historyWindow+1)
+	}
+	if reply := lastReply(t, env); reply.ParentId == 0 || reply.IsActive != 1 {
+		t.Fatalf("reply not appended to the active branch: %+v", reply)
+	}
+}
+
+func TestChatStopSavesTruncatedReply(t *testing.T) {
+	env := newTestEnv(t, fakeReply{chunks: []string{"今天", "下雨"}, hang: true})
+	conn := dialChat(t, env, 1, 1)
+
+	send(t, conn, WSMessageRequestTypeText, "你好")
+	responses := readUntil(t, conn, WSMessageResponseTypeDelta)
+	send(t, conn, WSMessageRequestTypeStop, "")
+	content, done := streamed(append(responses, readDone(t, conn)...))
+	if done.Reason != DoneReasonCancelled {
+		t.Fatalf("done reason = %q, want %q", done.Reason, DoneReasonCancelled)
+	}
+	// 已推送的部分作为截断的回复保存
+	if reply := lastReply(t, env); reply.Content != content || reply.Metadata != `{"truncated":true}` {
+		t.Fatalf("unexpected reply: %+v, streamed %q", reply, content)
+	}
+}
+
+func TestChatStopBeforeStreamKeepsConnection(t *testing.T) {
+	env := newTestEnv(t, fakeReply{wait: true}, fakeReply{chunks: []string{"你好"}})
+	conn := dialChat(t, env, 1, 1)
+
+	// LLM 尚未返回任何内容时停止，按取消处理而不是断开连接
+	send(t, conn, WSMessageRequestTypeText, "你好")
+	waitRequest(t, env)
+	send(t, conn, WSMessageRequestTypeStop, "")
+	if _, done := streamed(readDone(t, conn)); done.Reason != DoneReasonCancelled {
+		t.Fatalf("done reason = %q, want %q", done.Reason, DoneReasonCancelled)
+	}
+	if reply := lastReply(t, env); reply.Content != "" || reply.Metadata != `{"truncated":true}` {
+		t.Fatalf("unexpected reply: %+v", reply)
+	}
+
+	send(t, conn, WSMessageRequestTypeText, "还在吗")
+	if content, done := streamed(readDone(t, conn)); content != "你好" || done.Reason != "" {
+		t.Fatalf("streamed %q, done %+v", content, done)
+	}
+}
+
+func TestChatStreamErrorMarksTruncated(t *testing.T) {
+	env := newTestEnv(t, fakeReply{chunks: []string{"今天"}, err: "upstream failed"})
+	conn := dialChat(t, env, 1, 1)
+
+	send(t, conn, WSMessageRequestTypeText, "你好")
+	content, done := streamed(readDone(t, conn))
+	if content != "今天" || done.Reason != DoneReasonError {
+		t.Fatalf("streamed %q, done %+v", content, done)
+	}
+	if reply := lastReply(t, env); reply.Content != "今天" || reply.Metadata != `{"truncated":true}` {
+		t.Fatalf("unexpected reply: %+v", reply)
+	}
+}
+
+func TestChatStopWithQueuedRequests(t *testing.T) {
+	env := newTestEnv(t, fakeReply{chunks: []string{"今天"}, hang: true})
+	conn := dialChat(t, env, 1, 1)
+
+	send(t, conn, WSMessageRequestTypeText, "你好")
+	waitRequest(t, env)
+	// 排队的请求超出上限时读协程仍能收到 stop
+	for i := 0; i < maxPendingRequests+4; i++ {
+		send(t, conn, WSMessageRequestTypeText, "还在吗")
+	}
+	send(t, conn, WSMessageRequestTypeStop, "")
+	if _, done := streamed(readDone(t, conn)); done.Reason != DoneReasonCancelled {
+		t.Fatalf("done reason = %q, want %q", done.Reason, DoneReasonCancelled)
+	}
+}
+
+func TestChatRejectsOtherUsersSession(t *testing.T) {
+	env := newTestEnv(t)
+	env.sessions.sessions[1].UserId = 2
+
+	conn := dialChat(t, env, 1, 1)
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("connection to another user's session was not closed")
+	}
+}
+
+func TestChatRejectsPrivateCharacter(t *testing.T) {
+	env := newTestEnv(t)
+	env.characters.characters[10].IsPublic = 0
+
+	// 角色被创建者设为私有后，其他用户的已有会话不能继续对话
+	conn := dialChat(t, env, 1, 1)
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("connection to a private character's session was not closed")
+	}
+}
+
+func TestChatRegenerateCreatesAlternative(t *testing.T) {
+	env := newTestEnv(t, fakeReply{chunks: []string{"第一版"}}, fakeReply{chunks: []string{"第二版"}})
+	conn := dialChat(t, env, 1, 1)
+
+	send(t, conn, WSMessageRequestTypeText, "你好")
+	readDone(t, conn)
+	send(t, conn, WSMessageRequestTypeRegenerate, "")
+	responses := readDone(t, conn)
+	if content, done := streamed(responses); content != "第二版" || done.Reason != "" {
+		t.Fatalf("streamed %q, done %+v", content, done)
+	}
+
+	// 新回复与原回复都挂在同一条用户消息下，只有新回复被选中
+	messages := env.messages.all()
+	if len(messages) != 3 {
+		t.Fatalf("messages = %+v, want 3", messages)
+	}
+	userMsg, first, second := messages[0], messages[1], messages[2]
+	if first.ParentId != userMsg.Id || second.ParentId != userMsg.Id || first.IsSelected != 0 || second.IsSelected != 1 {
+		t.Fatalf("unexpected alternatives: %+v %+v", first, second)
+	}
+	// 重新生成的上下文不包含被替换的回复
+	for _, msg := range env.llm.lastMessages() {
+		if msg.Content == "第一版" {
+			t.Fatalf("regenerate context contains the replaced reply: %+v", env.llm.lastMessages())
+		}
+	}
+}
+
+func TestChatEditForksBranch(t *testing.T) {
+	env := newTestEnv(t, fakeReply{chunks: []string{"晴天"}}, fakeReply{chunks: []string{"好的"}}, fakeReply{chunks: []string{"雨天"}})
+	conn := dialChat(t, env, 1, 1)
+
+	send(t, conn, WSMessageRequestTypeText, "今天天气如何")
+	readDone(t, conn)
+	send(t, conn, WSMessageRequestTypeText, "谢谢")
+	readDone(t, conn)
+	edited := env.messages.all()[0]
+	if err := conn.WriteJSON(auth.WSRequest{Type: WSMessageRequestTypeEdit, Content: "明天天气如何", MessageId: edited.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if content, done := streamed(readDone(t, conn)); content != "雨天" || done.Reason != "" {
+		t.Fatalf("streamed %q, done %+v", content, done)
+	}
+
+	// 新的用户消息与被编辑的消息是兄弟节点，当前分支切换到新分支
+	path, _ := env.messages.FindActivePath(context.Background(), 1)
+	if len(path) != 2 || path[0].Content != "明天天气如何" || path[0].ParentId != edited.ParentId || path[1].Content != "雨天" {
+		t.Fatalf("unexpected active path: %+v", path)
+	}
+	if branches, _ := env.messages.FindChildren(context.Background(), 1, edited.ParentId); len(branches) != 2 {
+		t.Fatalf("branches = %+v, want 2", branches)
+	}
+	// 新分支的上下文不包含被编辑处之后的消息
+	for _, msg := range env.llm.lastMessages() {
+		if msg.Content == "今天天气如何" || msg.Content == "谢谢" {
+			t.Fatalf("edit context contains the old branch: %+v", env.llm.lastMessages())
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package chat
+
+import (
+	"qiniuyun/backend/app/internal/config"
+	"qiniuyun/backend/common/llm"
+	"qiniuyun/backend/model"
+	"strings"
+)
+
+const (
+	// summaryShare 剧情摘要最多占用预算的比例
+	summaryShare = 0.2
+	// memoryShare 检索到的记忆最多占用预算的比例
+	memoryShare = 0.2
+	// loreShare 触发的世界书条目最多占用预算的比例
+	loreShare = 0.15
+	// exampleShare 对话示例最多占用预算的比例
+	exampleShare = 0.1
+	// historyWindow 组装上下文时最多读取的当前分支上最近的消息数，更早的消息只通过剧情摘要保留
+	historyWindow = 200
+)
+
+// promptParts 组成系统提示词的各部分
+type promptParts struct {
+	systemPrompt string
+	// exampleDialogue 角色的对话示例，展示说话风格
+	exampleDialogue string
+	summary         string
+	// lore 被触发的世界书条目，按优先级从高到低
+	lore []*model.LorebookEntry
+	// memory 角色自身的记忆，userMemory 关于当前用户的记忆，均按相关度从高到低
+	memory     []string
+	userMemory []string
+	persona    persona
+	// placeholders 对以上各部分替换 {{user}} 与 {{char}}
+	placeholders llm.Placeholders
+}
+
+// contextBuilder 按 token 预算组装发送给 LLM 的上下文：系统提示词必定保留，
+// 摘要、世界书与记忆各自不超过预算的一定比例，剩余预算从最新的消息开始向前填充历史
+type contextBuilder struct {
+	tokenizer llm.Tokenizer
+	budget    int
+}
+
+func newContextBuilder(c config.LLM) *contextBuilder {
+	budget := c.ContextBudget
+	if limit := c.ContextLimit - c.ReplyTokens; limit < budget {
+		budget = limit
+	}
+	return &contextBuilder{
+		tokenizer: llm.NewTokenizer(c.Model),
+		budget:    budget,
+	}
+}
+
+func (b *contextBuilder) build(parts promptParts, messages []*model.Message) []llm.Message {
+	replace := parts.placeholders.Replace
+	systemPrompt := replace(parts.systemPrompt)
+	summary := replace(parts.summary)
+	memory, userMemory := replaceAll(replace, parts.memory), replaceAll(replace, parts.userMemory)
+	if parts.persona.name != "" {
+		systemPrompt += "\n\n=== The User You Are Talking To ===\nName: " + parts.persona.name
+		if parts.persona.description != "" {
+			systemPrompt += "\n" + replace(parts.persona.description)
+		}
+		systemPrompt += "\n=== End of User ==="
+	}
+	if parts.exampleDialogue != "" {
+		example := b.truncate(replace(parts.exampleDialogue), int(float64(b.budget)*exampleShare))
+		systemPrompt += "\n\n=== Example Dialogue ===\n" + example + "\n=== End of Example Dialogue ==="
+	}
+	remaining := b.budget - b.tokenizer.Count(systemPrompt)
+
+	// 世界书按优先级依次加入，预算不足时丢弃优先级较低的
+	var before, after []string
+	loreBudget := int(float64(b.budget) * loreShare)
+	for _, entry := range parts.lore {
+		content := replace(entry.Content)
+		cost := b.tokenizer.Count(content) + 1
+		if cost > loreBudget {
+			break
+		}
+		loreBudget -= cost
+		remaining -= cost
+		if entry.Position == LorePositionBefore {
+			before = append(before, content)
+		} else {
+			after = append(after, content)
+		}
+	}
+	if len(before) > 0 {
+		systemPrompt = strings.Join(before, "\n") + "\n\n" + systemPrompt
+	}
+	if len(after) > 0 {
+		systemPrompt += "\n\n" + strings.Join(after, "\n")
+	}
+
+	fullSystemPrompt := systemPrompt
+	if summary != "" {
+		summary = b.truncate(summary, int(float64(b.budget)*summaryShare))
+		section := "\n\n=== Story So Far ===\n" + summary + "\n=== End of Story So Far ==="
+		fullSystemPrompt += section
+		remaining -= b.tokenizer.Count(section)
+	}
+
+	// 记忆按相关度排序，预算不足时丢弃相关度较低的
+	memoryBudget := int(float64(b.budget) * memoryShare)
+	memory = b.fit(memory, &memoryBudget)
+	userMemory = b.fit(userMemory, &memoryBudget)
+	memoryContent := ""
+	if len(memory) > 0 {
+		memoryContent = "=== Relevant Memory ===\n" + strings.Join(memory, "\n") + "\n=== End of Memory ==="
+	}
+	if len(userMemory) > 0 {
+		if memoryContent != "" {
+			memoryContent += "\n\n"
+		}
+		memoryContent += "=== What You Remember About The User ===\n" + strings.Join(userMemory, "\n") + "\n=== End of Memory ==="
+	}
+	if memoryContent != "" {
+		fullSystemPrompt += "\n\n" + memoryContent
+		remaining -= b.tokenizer.Count(memoryContent)
+	}
+
+	start := b.fill(messages, remaining)
+	chatMessages := make([]llm.Message, 0, len(messages)-start+1)
+	chatMessages = append(chatMessages, llm.Message{
+		Role:    RoleSystem,
+		Content: fullSystemPrompt,
+	})
+	for _, msg := range messages[start:] {
+		chatMessages = append(chatMessages, llm.Message{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+	return chatMessages
+}
+
+// fill 从最新的消息向前填充 remaining 个 token，返回保留的最早一条消息的下标，最新一条无论如何都保留
+func (b *contextBuilder) fill(messages []*model.Message, remaining int) int {
+	start := len(messages)
+	for start > 0 {
+		cost := llm.CountMessage(b.tokenizer, llm.Message{Role: messages[start-1].Role, Content: messages[start-1].Content})
+		if cost > remaining && start < len(messages) {
+			break
+		}
+		remaining -= cost
+		start--
+	}
+	return start
+}
+
+// historyStart 估计 build 至少会保留的最早一条消息的下标：假设摘要、记忆、世界书与对话示例都用满各自的比例，
+// 且不早于最近 historyWindow 条消息。剧情摘要以此为界，被挤出上下文的消息才会并入摘要
+func (b *contextBuilder) historyStart(systemPrompt string, messages []*model.Message) int {
+	reserved := int(float64(b.budget) * (summaryShare + memoryShare + loreShare + exampleShare))
+	return max(b.fill(messages, b.budget-b.tokenizer.Count(systemPrompt)-reserved), len(messages)-historyWindow)
+}
+
+// characterPrompt 角色的系统提示词，尚未生成完成或生成失败时用名称、介绍、性格与背景临时拼接
+func characterPrompt(character *model.Character) string {
+	if character.SystemPrompt != "" {
+		return character.SystemPrompt
+	}
+	prompt := "你将扮演" + character.Name + "，始终以该角色的身份与语气和用户对话。"
+	if character.Description != "" {
+		prompt += "\n角色介绍：" + character.Description
+	}
+	if len(character.Personality) > 0 {
+		prompt += "\n性格：" + strings.Join(character.Personality, "、")
+	}
+	if character.Background != "" {
+		prompt += "\n背景：" + character.Background
+	}
+	return prompt
+}
+
+func replaceAll(replace func(string) string, texts []string) []string {
+	res := make([]string, 0, len(texts))
+	for _, text := range texts {
+		res = append(res, replace(text))
+	}
+	return res
+}
+
+// fit 依次保留 texts 直到超出 budget，并扣减 budget
+func (b *contextBuilder) fit(texts []string, budget *int) []string {
+	var res []string
+	for _, text := range texts {
+		cost := b.tokenizer.Count(text) + 1
+		if cost > *budget {
+			break
+		}
+		*budget -= cost
+		res = append(res, text)
+	}
+	return res
+}
+
+// truncate 截断 text 使其不超过 budget，保留开头部分
+func (b *contextBuilder) truncate(text string, budget int) string {
+	if b.tokenizer.Count(text) <= budget {
+		return text
+	}
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if b.tokenizer.Count(string(runes[:mid])) <= budget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo]) + "…"
+}
@@ -0,0 +1,160 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"qiniuyun/backend/app/internal/config"
+	"qiniuyun/backend/common/llm"
+	"qiniuyun/backend/model"
+)
+
+// messagesOf 依次生成内容为 contents 的消息，每个汉字计 1 个 token，每条消息另有 4 个 token 的开销
+func messagesOf(contents ...string) []*model.Message {
+	var res []*model.Message
+	for i, content := range contents {
+		role := RoleUser
+		if i%2 == 1 {
+			role = RoleAssistant
+		}
+		res = append(res, &model.Message{Id: int64(i + 1), Role: role, Content: content})
+	}
+	return res
+}
+
+func TestContextBuilderBuild(t *testing.T) {
+	tests := []struct {
+		name       string
+		budget     int
+		summary    string
+		memory     []string
+		userMemory []string
+		lore       []*model.LorebookEntry
+		messages   []*model.Message
+		// wantHistory 保留的历史消息内容
+		wantHistory []string
+		// wantSystem 系统提示词中应包含的内容，notSystem 不应包含的内容
+		wantSystem []string
+		notSystem  []string
+	}{
+		{
+			name:        "everything fits",
+			budget:      100,
+			messages:    messagesOf("一二三", "四五六"),
+			wantHistory: []string{"一二三", "四五六"},
+		},
+		{
+			name:        "history filled from the newest",
+			budget:      20,
+			messages:    messagesOf("一二三四五", "六七八九十", "甲乙丙丁戊"),
+			wantHistory: []string{"六七八九十", "甲乙丙丁戊"},
+		},
+		{
+			name:        "newest message kept over budget",
+			budget:      5,
+			messages:    messagesOf("一二三四五", "六七八九十甲乙丙丁戊"),
+			wantHistory: []string{"六七八九十甲乙丙丁戊"},
+		},
+		{
+			name:        "summary truncated to its share",
+			budget:      50,
+			summary:     strings.Repeat("雨", 30),
+			messages:    messagesOf("你好"),
+			wantHistory: []string{"你好"},
+			wantSystem:  []string{strings.Repeat("雨", 10) + "…"},
+			notSystem:   []string{strings.Repeat("雨", 11)},
+		},
+		{
+			name:        "less relevant memory dropped",
+			budget:      50,
+			memory:      []string{"艾拉喜欢雨天", "艾拉住在港口"},
+			messages:    messagesOf("你好"),
+			wantHistory: []string{"你好"},
+			wantSystem:  []string{"艾拉喜欢雨天"},
+			notSystem:   []string{"艾拉住在港口"},
+		},
+		{
+			name:        "user memory shares the memory budget",
+			budget:      60,
+			memory:      []string{"艾拉喜欢雨"},
+			userMemory:  []string{"用户喜欢晴", "用户养了猫"},
+			messages:    messagesOf("你好"),
+			wantHistory: []string{"你好"},
+			wantSystem:  []string{"艾拉喜欢雨", "用户喜欢晴"},
+			notSystem:   []string{"用户养了猫"},
+		},
+		{
+			name:   "lore placed around the system prompt",
+			budget: 100,
+			lore: []*model.LorebookEntry{
+				{Content: "港口终年下雨", Position: LorePositionBefore},
+				{Content: "灯塔已经废弃", Position: LorePositionAfter},
+			},
+			messages:    messagesOf("你好"),
+			wantHistory: []string{"你好"},
+			wantSystem:  []string{"港口终年下雨\n\n系统", "系统\n\n灯塔已经废弃"},
+		},
+		{
+			name:   "lower priority lore dropped",
+			budget: 100,
+			lore: []*model.LorebookEntry{
+				{Content: "港口终年下雨，雾气很重", Position: LorePositionAfter},
+				{Content: "灯塔已经废弃", Position: LorePositionAfter},
+			},
+			messages:    messagesOf("你好"),
+			wantHistory: []string{"你好"},
+			wantSystem:  []string{"港口终年下雨，雾气很重"},
+			notSystem:   []string{"灯塔已经废弃"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &contextBuilder{tokenizer: llm.NewTokenizer(""), budget: tt.budget}
+			res := b.build(promptParts{
+				systemPrompt: "系统",
+				summary:      tt.summary,
+				lore:         tt.lore,
+				memory:       tt.memory,
+				userMemory:   tt.userMemory,
+			}, tt.messages)
+			if res[0].Role != RoleSystem || !strings.Contains(res[0].Content, "系统") {
+				t.Fatalf("system prompt missing: %+v", res[0])
+			}
+			var history []string
+			for _, msg := range res[1:] {
+				history = append(history, msg.Content)
+			}
+			if strings.Join(history, "|") != strings.Join(tt.wantHistory, "|") {
+				t.Fatalf("history = %q, want %q", history, tt.wantHistory)
+			}
+			for _, s := range tt.wantSystem {
+				if !strings.Contains(res[0].Content, s) {
+					t.Fatalf("system prompt %q does not contain %q", res[0].Content, s)
+				}
+			}
+			for _, s := range tt.notSystem {
+				if strings.Contains(res[0].Content, s) {
+					t.Fatalf("system prompt %q contains %q", res[0].Content, s)
+				}
+			}
+		})
+	}
+}
+
+func TestNewContextBuilderBudget(t *testing.T) {
+	tests := []struct {
+		name string
+		c    config.LLM
+		want int
+	}{
+		{name: "configured budget", c: config.LLM{ContextLimit: 32768, ContextBudget: 6000, ReplyTokens: 1024}, want: 6000},
+		{name: "limited by the model", c: config.LLM{ContextLimit: 4096, ContextBudget: 6000, ReplyTokens: 1024}, want: 3072},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newContextBuilder(tt.c).budget; got != tt.want {
+				t.Fatalf("budget = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package chat
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/common/globalkey"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type DeleteSessionLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDeleteSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DeleteSessionLogic {
+	return &DeleteSessionLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// DeleteSession 删除会话及其全部消息，并删除从该会话中提取的长期记忆
+func (l *DeleteSessionLogic) DeleteSession(req *types.DeleteSessionRequest) error {
+	session, err := findOwnedSession(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	// 先删除会话行并持有行锁：写入新消息的事务会在 Touch 时等待并因会话不存在而回滚，
+	// 已先写入的消息则在其提交后由 DeleteBySession 一并删除
+	err = l.svcCtx.SessionModel.Transaction(l.ctx, func(db *gorm.DB) error {
+		if e := l.svcCtx.SessionModel.Delete(l.ctx, db, session.Id); e != nil {
+			return e
+		}
+		return l.svcCtx.MessageModel.DeleteBySession(l.ctx, db, session.Id)
+	})
+	if err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "sessionId: %v,err: %+v", session.Id, err)
+	}
+	// 会话已删除，记忆删除失败不影响结果
+	if err = l.svcCtx.Embedding.DeleteSession(l.ctx, globalkey.Collection(session.CharacterId), session.Id); err != nil {
+		l.Error(err)
+	}
+	return nil
+}
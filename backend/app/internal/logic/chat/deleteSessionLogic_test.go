@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/embedding"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/common/globalkey"
+	"qiniuyun/backend/model"
+)
+
+func TestDeleteSessionRemovesMessagesAndMemories(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.sessions.sessions[2] = &model.Session{Id: 2, CharacterId: 10, UserId: 1}
+	insertBranches(env)
+	_ = env.messages.Insert(ctx, nil, &model.Message{SessionId: 2, Role: RoleUser, Content: "另一个会话", IsSelected: 1, IsActive: 1})
+	const memory = "旅行者喜欢下雨天"
+	vector, _ := env.svcCtx.Embedding.GetEmbedding(memory)
+	for _, sessionId := range []int64{1, 2} {
+		payload := embedding.Payload{UserId: 1, SessionId: sessionId, Source: embedding.SourceConversation}
+		if err := env.svcCtx.Embedding.InsertVectors(ctx, globalkey.Collection(10), []string{memory}, [][]float32{vector}, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 其他用户不能删除
+	err := NewDeleteSessionLogic(userContext(2), env.svcCtx).DeleteSession(&types.DeleteSessionRequest{Id: 1})
+	var codeErr *errorz.CodeError
+	if !errors.As(err, &codeErr) || codeErr.GetErrCode() != errorz.REQUEST_ROLE_ERROR {
+		t.Fatalf("err = %v, want REQUEST_ROLE_ERROR", err)
+	}
+
+	if err = NewDeleteSessionLogic(userContext(1), env.svcCtx).DeleteSession(&types.DeleteSessionRequest{Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = env.sessions.FindOne(ctx, 1); err != model.ErrNotFound {
+		t.Fatalf("session not deleted: %v", err)
+	}
+	// 只删除该会话的消息与记忆
+	if messages := env.messages.all(); len(messages) != 1 || messages[0].SessionId != 2 {
+		t.Fatalf("remaining messages = %+v", messages)
+	}
+	memories, _ := env.svcCtx.Embedding.Search(globalkey.Collection(10), vector, 1)
+	if len(memories) != 1 || memories[0].SessionId != 2 {
+		t.Fatalf("remaining memories = %+v", memories)
+	}
+}
+
+func TestArchiveAndPinSession(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := userContext(1)
+
+	if err := NewArchiveSessionLogic(ctx, env.svcCtx).ArchiveSession(&types.ArchiveSessionRequest{Id: 1, Archived: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewPinSessionLogic(ctx, env.svcCtx).PinSession(&types.PinSessionRequest{Id: 1, Pinned: true}); err != nil {
+		t.Fatal(err)
+	}
+	if session, _ := env.sessions.FindOne(ctx, 1); session.IsArchived != 1 || session.IsPinned != 1 {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+	if err := NewArchiveSessionLogic(ctx, env.svcCtx).ArchiveSession(&types.ArchiveSessionRequest{Id: 1, Archived: false}); err != nil {
+		t.Fatal(err)
+	}
+	if session, _ := env.sessions.FindOne(ctx, 1); session.IsArchived != 0 || session.IsPinned != 1 {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+}
@@ -0,0 +1,510 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"qiniuyun/backend/app/internal/config"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/common/embedding"
+	"qiniuyun/backend/common/job"
+	"qiniuyun/backend/common/llm"
+	"qiniuyun/backend/model"
+)
+
+// 以下为测试用的内存模型，只实现被测逻辑用到的方法，其余方法调用时 panic
+
+type fakeCharacterModel struct {
+	model.CharacterModel
+	characters map[int64]*model.Character
+}
+
+func (m *fakeCharacterModel) FindOne(ctx context.Context, id int64) (*model.Character, error) {
+	if c, ok := m.characters[id]; ok && c.IsPublic == 1 {
+		res := *c
+		return &res, nil
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *fakeCharacterModel) FindOneByUser(ctx context.Context, id int64, userId int64) (*model.Character, error) {
+	if c, ok := m.characters[id]; ok && (c.IsPublic == 1 || c.UserId == userId) {
+		res := *c
+		return &res, nil
+	}
+	return nil, model.ErrNotFound
+}
+
+type fakeLorebookModel struct {
+	model.LorebookEntryModel
+	entries []*model.LorebookEntry
+}
+
+func (m *fakeLorebookModel) FindByCharacter(ctx context.Context, characterId int64) ([]*model.LorebookEntry, error) {
+	var res []*model.LorebookEntry
+	for _, entry := range m.entries {
+		if entry.CharacterId == characterId {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+type fakeUserModel struct {
+	model.UserModel
+	users map[int64]*model.User
+}
+
+func (m *fakeUserModel) FindOne(ctx context.Context, id int64) (*model.User, error) {
+	if u, ok := m.users[id]; ok {
+		res := *u
+		return &res, nil
+	}
+	return nil, model.ErrNotFound
+}
+
+type fakePersonaModel struct {
+	model.PersonaModel
+	personas map[int64]*model.Persona
+}
+
+func (m *fakePersonaModel) FindOne(ctx context.Context, id int64) (*model.Persona, error) {
+	if p, ok := m.personas[id]; ok {
+		res := *p
+		return &res, nil
+	}
+	return nil, model.ErrNotFound
+}
+
+type fakeSessionModel struct {
+	model.SessionModel
+	mu       sync.Mutex
+	sessions map[int64]*model.Session
+}
+
+func (m *fakeSessionModel) FindOne(ctx context.Context, id int64) (*model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[id]; ok {
+		res := *s
+		return &res, nil
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *fakeSessionModel) Insert(ctx context.Context, tx *gorm.DB, data *model.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data.Id = int64(len(m.sessions) + 1)
+	res := *data
+	m.sessions[data.Id] = &res
+	return nil
+}
+
+func (m *fakeSessionModel) UpdateColumns(ctx context.Context, tx *gorm.DB, id int64, columns map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.sessions[id]
+	for column, value := range columns {
+		switch column {
+		case "memory_cursor":
+			s.MemoryCursor = value.(int64)
+		case "summary":
+			s.Summary = value.(string)
+		case "summary_cursor":
+			s.SummaryCursor = value.(int64)
+		case "title":
+			s.Title = value.(string)
+		case "title_source":
+			s.TitleSource = value.(int64)
+		case "is_archived":
+			s.IsArchived = castSelected(value.(bool))
+		case "is_pinned":
+			s.IsPinned = castSelected(value.(bool))
+		default:
+			panic("unexpected column " + column)
+		}
+	}
+	return nil
+}
+
+// FindByUser 与 SQL 相同的排序与游标条件
+func (m *fakeSessionModel) FindByUser(ctx context.Context, userId int64, filter model.SessionFilter, after *model.SessionCursor, pageSize int64) ([]*model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []*model.Session
+	for _, s := range m.sessions {
+		if s.UserId != userId || (s.IsArchived == 1) != filter.Archived || (filter.CharacterId != 0 && s.CharacterId != filter.CharacterId) {
+			continue
+		}
+		if after != nil && compareSession(s, after) <= 0 {
+			continue
+		}
+		c := *s
+		res = append(res, &c)
+	}
+	slices.SortFunc(res, func(a, b *model.Session) int {
+		return compareSession(a, &model.SessionCursor{IsPinned: b.IsPinned, UpdatedAt: b.UpdatedAt, Id: b.Id})
+	})
+	return res[:min(int64(len(res)), pageSize)], nil
+}
+
+// compareSession 按列表顺序比较，排在 cursor 之后时返回正数
+func compareSession(s *model.Session, cursor *model.SessionCursor) int {
+	if s.IsPinned != cursor.IsPinned {
+		return int(cursor.IsPinned - s.IsPinned)
+	}
+	if !s.UpdatedAt.Equal(cursor.UpdatedAt) {
+		return cursor.UpdatedAt.Compare(s.UpdatedAt)
+	}
+	return int(cursor.Id - s.Id)
+}
+
+func (m *fakeSessionModel) Touch(ctx context.Context, tx *gorm.DB, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return model.ErrNotFound
+	}
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *fakeSessionModel) Transaction(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(nil)
+}
+
+func (m *fakeSessionModel) Delete(ctx context.Context, tx *gorm.DB, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *fakeSessionModel) UpdateGeneratedTitle(ctx context.Context, id int64, title string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.sessions[id]; s.TitleSource == model.SessionTitleOpening {
+		s.Title, s.TitleSource = title, model.SessionTitleGenerated
+	}
+	return nil
+}
+
+type fakeMessageModel struct {
+	model.MessageModel
+	mu       sync.Mutex
+	messages []*model.Message
+}
+
+func (m *fakeMessageModel) Transaction(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return fn(nil)
+}
+
+func (m *fakeMessageModel) Insert(ctx context.Context, tx *gorm.DB, data *model.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data.Id = 1
+	if n := len(m.messages); n > 0 {
+		data.Id = m.messages[n-1].Id + 1
+	}
+	res := *data
+	m.messages = append(m.messages, &res)
+	return nil
+}
+
+func (m *fakeMessageModel) FindBySession(ctx context.Context, sessionId int64) ([]*model.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []*model.Message
+	for _, msg := range m.messages {
+		if msg.SessionId == sessionId {
+			c := *msg
+			res = append(res, &c)
+		}
+	}
+	return res, nil
+}
+
+func (m *fakeMessageModel) FindOne(ctx context.Context, id int64) (*model.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, msg := range m.messages {
+		if msg.Id == id {
+			res := *msg
+			return &res, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *fakeMessageModel) Update(ctx context.Context, tx *gorm.DB, data *model.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, msg := range m.messages {
+		if msg.Id == data.Id {
+			res := *data
+			m.messages[i] = &res
+			return nil
+		}
+	}
+	return model.ErrNotFound
+}
+
+func (m *fakeMessageModel) FindActivePath(ctx context.Context, sessionId int64) ([]*model.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []*model.Message
+	for _, msg := range m.messages {
+		if msg.SessionId == sessionId && msg.IsActive == 1 {
+			c := *msg
+			res = append(res, &c)
+		}
+	}
+	return res, nil
+}
+
+func (m *fakeMessageModel) FindPage(ctx context.Context, sessionId int64, before int64, after int64, limit int) ([]*model.Message, error) {
+	path, _ := m.FindActivePath(ctx, sessionId)
+	switch {
+	case after != 0:
+		path = slices.DeleteFunc(path, func(msg *model.Message) bool { return msg.Id <= after })
+		return path[:min(limit, len(path))], nil
+	case before != 0:
+		path = slices.DeleteFunc(path, func(msg *model.Message) bool { return msg.Id >= before })
+	}
+	return path[max(0, len(path)-limit):], nil
+}
+
+func (m *fakeMessageModel) FindActiveRange(ctx context.Context, sessionId int64, after int64, before int64, limit int) ([]*model.Message, error) {
+	path, _ := m.FindActivePath(ctx, sessionId)
+	path = slices.DeleteFunc(path, func(msg *model.Message) bool {
+		return msg.Id <= after || (before != 0 && msg.Id >= before)
+	})
+	return path[:min(limit, len(path))], nil
+}
+
+func (m *fakeMessageModel) FindChildren(ctx context.Context, sessionId int64, parentId int64) ([]*model.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []*model.Message
+	for _, msg := range m.messages {
+		if msg.SessionId == sessionId && msg.ParentId == parentId {
+			c := *msg
+			res = append(res, &c)
+		}
+	}
+	return res, nil
+}
+
+// SelectChild 与 SQL 相同地在 parentId 位于当前分支上时切换当前分支
+func (m *fakeMessageModel) SelectChild(ctx context.Context, tx *gorm.DB, sessionId int64, parentId int64, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, msg := range m.messages {
+		if msg.SessionId == sessionId && msg.ParentId == parentId {
+			msg.IsSelected = castSelected(msg.Id == id)
+		}
+	}
+	if parentId != 0 && !slices.ContainsFunc(m.messages, func(msg *model.Message) bool { return msg.Id == parentId && msg.IsActive == 1 }) {
+		return nil
+	}
+	for _, msg := range m.messages {
+		if msg.SessionId == sessionId && msg.IsActive == 1 && msg.Id > parentId {
+			msg.IsActive = 0
+		}
+	}
+	for next := id; next != 0; {
+		idx := slices.IndexFunc(m.messages, func(msg *model.Message) bool { return msg.Id == next })
+		m.messages[idx].IsActive = 1
+		next = 0
+		for _, msg := range m.messages {
+			if msg.SessionId == sessionId && msg.ParentId == m.messages[idx].Id && msg.IsSelected == 1 {
+				next = msg.Id
+			}
+		}
+	}
+	return nil
+}
+
+func (m *fakeMessageModel) DeleteBySession(ctx context.Context, tx *gorm.DB, sessionId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = slices.DeleteFunc(m.messages, func(msg *model.Message) bool {
+		return msg.SessionId == sessionId
+	})
+	return nil
+}
+
+// all 返回全部消息的副本
+func (m *fakeMessageModel) all() []model.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []model.Message
+	for _, msg := range m.messages {
+		res = append(res, *msg)
+	}
+	return res
+}
+
+func castSelected(selected bool) int64 {
+	if selected {
+		return 1
+	}
+	return 0
+}
+
+// fakeReply fakeLLM 对一次对话请求的回应
+type fakeReply struct {
+	chunks []string
+	// wait 为 true 时不返回任何内容，直到客户端取消请求
+	wait bool
+	// hang 为 true 时发送完 chunks 后不结束流，直到客户端取消请求
+	hang bool
+	// err 不为空时发送完 chunks 后在流中返回该错误
+	err string
+}
+
+// fakeLLM 测试用的 llm.Provider，按顺序为每次对话请求使用一个 fakeReply，用完后回复"好的"
+type fakeLLM struct {
+	mu       sync.Mutex
+	replies  []fakeReply
+	requests chan struct{}
+	closed   chan struct{}
+	last     []llm.Message
+}
+
+func newFakeLLM(t *testing.T, replies ...fakeReply) *fakeLLM {
+	f := &fakeLLM{replies: replies, requests: make(chan struct{}, 64), closed: make(chan struct{})}
+	t.Cleanup(func() { close(f.closed) })
+	return f
+}
+
+func (f *fakeLLM) next() fakeReply {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.replies) == 0 {
+		return fakeReply{chunks: []string{"好的"}}
+	}
+	reply := f.replies[0]
+	f.replies = f.replies[1:]
+	return reply
+}
+
+func (f *fakeLLM) Complete(ctx context.Context, messages []llm.Message) (string, error) {
+	return strings.Join(f.next().chunks, ""), nil
+}
+
+func (f *fakeLLM) CompleteJSON(ctx context.Context, messages []llm.Message, v interface{}) error {
+	return llm.NewFake(nil).CompleteJSON(ctx, messages, v)
+}
+
+func (f *fakeLLM) Stream(ctx context.Context, messages []llm.Message) (llm.Stream, error) {
+	f.mu.Lock()
+	f.last = messages
+	f.mu.Unlock()
+	reply := f.next()
+	f.requests <- struct{}{}
+	if reply.wait {
+		f.block(ctx)
+		return nil, context.Cause(ctx)
+	}
+	return &fakeLLMStream{ctx: ctx, llm: f, reply: reply}, nil
+}
+
+// lastMessages 返回最后一次对话请求的上下文
+func (f *fakeLLM) lastMessages() []llm.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.last
+}
+
+// block 等待请求被取消或测试结束
+func (f *fakeLLM) block(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-f.closed:
+	}
+}
+
+type fakeLLMStream struct {
+	ctx   context.Context
+	llm   *fakeLLM
+	reply fakeReply
+	pos   int
+}
+
+func (s *fakeLLMStream) Recv() (string, error) {
+	if s.pos < len(s.reply.chunks) {
+		s.pos++
+		return s.reply.chunks[s.pos-1], nil
+	}
+	switch {
+	case s.reply.err != "":
+		return "", errors.New(s.reply.err)
+	case s.reply.hang:
+		s.llm.block(s.ctx)
+		return "", context.Cause(s.ctx)
+	default:
+		return "", io.EOF
+	}
+}
+
+func (s *fakeLLMStream) Close() error {
+	return nil
+}
+
+// testEnv 使用内存模型、fakeLLM 与内存向量存储的服务上下文
+type testEnv struct {
+	svcCtx     *svc.ServiceContext
+	llm        *fakeLLM
+	characters *fakeCharacterModel
+	lorebook   *fakeLorebookModel
+	users      *fakeUserModel
+	personas   *fakePersonaModel
+	sessions   *fakeSessionModel
+	messages   *fakeMessageModel
+}
+
+func newTestEnv(t *testing.T, replies ...fakeReply) *testEnv {
+	t.Helper()
+	env := &testEnv{
+		llm:        newFakeLLM(t, replies...),
+		characters: &fakeCharacterModel{characters: make(map[int64]*model.Character)},
+		lorebook:   &fakeLorebookModel{},
+		users:      &fakeUserModel{users: make(map[int64]*model.User)},
+		personas:   &fakePersonaModel{personas: make(map[int64]*model.Persona)},
+		sessions:   &fakeSessionModel{sessions: make(map[int64]*model.Session)},
+		messages:   &fakeMessageModel{},
+	}
+	env.svcCtx = &svc.ServiceContext{
+		Config: config.Config{
+			LLM: config.LLM{ContextLimit: 32768, ContextBudget: 200, ReplyTokens: 1024},
+		},
+		CharacterModel: env.characters,
+		LorebookModel:  env.lorebook,
+		UserModel:      env.users,
+		PersonaModel:   env.personas,
+		SessionModel:   env.sessions,
+		MessageModel:   env.messages,
+		LLM:            llm.New(env.llm),
+		Embedding:      embedding.New(embedding.NewHashEmbedder(256), embedding.NewMemoryStore()),
+		Jobs:           job.NewDispatcher(job.NewMemory(), job.NewMemoryStore(), 1, 1),
+	}
+	env.svcCtx.Jobs.Handle(JobExtractMemories, HandleExtractMemories(env.svcCtx))
+	env.svcCtx.Jobs.Handle(JobSummarize, HandleSummarize(env.svcCtx))
+	env.svcCtx.Jobs.Handle(JobGenerateTitle, HandleGenerateTitle(env.svcCtx))
+	env.svcCtx.Jobs.Start()
+	t.Cleanup(env.svcCtx.Jobs.Stop)
+	env.characters.characters[10] = &model.Character{Id: 10, UserId: 2, Name: "艾拉", IsPublic: 1, SystemPrompt: "你是艾拉。"}
+	env.users.users[1] = &model.User{Id: 1, Name: "小林"}
+	env.sessions.sessions[1] = &model.Session{Id: 1, CharacterId: 10, UserId: 1}
+	return env
+}
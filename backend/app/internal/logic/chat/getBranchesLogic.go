@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetBranchesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetBranchesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetBranchesLogic {
+	return &GetBranchesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// GetBranches 返回与指定消息同一父消息下的全部分支（包含其自身）
+func (l *GetBranchesLogic) GetBranches(req *types.GetBranchesRequest) (resp *types.GetBranchesResponse, err error) {
+	msg, err := findOwnedMessage(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	branches, err := l.svcCtx.MessageModel.FindChildren(l.ctx, msg.SessionId, msg.ParentId)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "messageId: %v,err: %+v", msg.Id, err)
+	}
+	return &types.GetBranchesResponse{Branches: castMessages(branches)}, nil
+}
+
+func castMessages(messages []*model.Message) []types.Message {
+	res := make([]types.Message, 0)
+	for _, msg := range messages {
+		res = append(res, types.Message{
+			Id:         msg.Id,
+			SessionId:  msg.SessionId,
+			ParentId:   msg.ParentId,
+			Role:       msg.Role,
+			Content:    msg.Content,
+			IsSelected: msg.IsSelected == 1,
+			CreatedAt:  msg.CreatedAt.Unix(),
+		})
+	}
+	return res
+}
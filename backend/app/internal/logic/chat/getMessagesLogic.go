@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// messagePageSize 每页消息数的默认值，连接 WebSocket 时也只推送最新的这么多条消息
+	messagePageSize = 50
+)
+
+type GetMessagesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetMessagesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetMessagesLogic {
+	return &GetMessagesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// GetMessages 按消息 id 游标分页返回会话的消息，before 向前翻页、after 向后翻页，不能同时指定
+func (l *GetMessagesLogic) GetMessages(req *types.GetMessagesRequest) (resp *types.GetMessagesResponse, err error) {
+	if req.Before != 0 && req.After != 0 {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "before: %d, after: %d", req.Before, req.After)
+	}
+	session, err := findOwnedSession(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = messagePageSize
+	}
+	messages, hasMore, err := findMessagePage(l.ctx, l.svcCtx, session.Id, req.Before, req.After, limit)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "sessionId: %v,err: %+v", session.Id, err)
+	}
+	return &types.GetMessagesResponse{
+		Messages: castMessages(messages),
+		HasMore:  hasMore,
+	}, nil
+}
+
+// findMessagePage 多取一条判断翻页方向上是否还有更多消息，结果按 id 升序
+func findMessagePage(ctx context.Context, svcCtx *svc.ServiceContext, sessionId, before, after int64, limit int) ([]*model.Message, bool, error) {
+	messages, err := svcCtx.MessageModel.FindPage(ctx, sessionId, before, after, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(messages) <= limit {
+		return messages, false, nil
+	}
+	if after != 0 {
+		return messages[:limit], true, nil
+	}
+	return messages[1:], true, nil
+}
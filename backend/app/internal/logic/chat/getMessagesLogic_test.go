@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+)
+
+// insertChain 在会话 1 中写入 n 条依次相连的消息，返回它们的 id
+func insertChain(env *testEnv, n int) []int64 {
+	var ids []int64
+	parentId := int64(0)
+	for i := 0; i < n; i++ {
+		msg := &model.Message{SessionId: 1, ParentId: parentId, Role: RoleUser, Content: "你好", IsSelected: 1, IsActive: 1}
+		_ = env.messages.Insert(context.Background(), nil, msg)
+		ids = append(ids, msg.Id)
+		parentId = msg.Id
+	}
+	return ids
+}
+
+func messageIds(messages []types.Message) []int64 {
+	var ids []int64
+	for _, msg := range messages {
+		ids = append(ids, msg.Id)
+	}
+	return ids
+}
+
+func TestGetMessagesPaginates(t *testing.T) {
+	env := newTestEnv(t)
+	ids := insertChain(env, 5)
+	logic := NewGetMessagesLogic(userContext(1), env.svcCtx)
+
+	tests := []struct {
+		name    string
+		req     types.GetMessagesRequest
+		want    []int64
+		hasMore bool
+	}{
+		{"latest", types.GetMessagesRequest{Id: 1, Limit: 2}, ids[3:], true},
+		{"before", types.GetMessagesRequest{Id: 1, Before: ids[3], Limit: 2}, ids[1:3], true},
+		{"before first page", types.GetMessagesRequest{Id: 1, Before: ids[2], Limit: 2}, ids[:2], false},
+		{"after", types.GetMessagesRequest{Id: 1, After: ids[0], Limit: 2}, ids[1:3], true},
+		{"after last page", types.GetMessagesRequest{Id: 1, After: ids[2]}, ids[3:], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := logic.GetMessages(&tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := messageIds(resp.Messages); !slices.Equal(got, tt.want) || resp.HasMore != tt.hasMore {
+				t.Fatalf("messages = %v, has_more = %v, want %v, %v", got, resp.HasMore, tt.want, tt.hasMore)
+			}
+		})
+	}
+}
+
+func TestGetMessagesRejectsBothCursors(t *testing.T) {
+	env := newTestEnv(t)
+	_, err := NewGetMessagesLogic(userContext(1), env.svcCtx).GetMessages(&types.GetMessagesRequest{Id: 1, Before: 3, After: 1})
+	var codeErr *errorz.CodeError
+	if !errors.As(err, &codeErr) || codeErr.GetErrCode() != errorz.REUQEST_PARAM_ERROR {
+		t.Fatalf("err = %v, want REUQEST_PARAM_ERROR", err)
+	}
+}
@@ -2,8 +2,14 @@ package chat
 
 import (
 	"context"
+	"fmt"
+	"github.com/pkg/errors"
 	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
 	"qiniuyun/backend/model"
+	"strconv"
+	"strings"
+	"time"
 
 	"qiniuyun/backend/app/internal/svc"
 	"qiniuyun/backend/app/internal/types"
@@ -25,13 +31,59 @@ func NewGetSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetSes
 	}
 }
 
+// GetSession 按角色与归档状态筛选当前用户的会话，置顶的会话在前，其余按最近活动时间倒序。
+// 返回的 next_cursor 为本页最后一个会话的排序键，翻页期间有会话收到新消息时其余会话不会被跳过或重复
 func (l *GetSessionLogic) GetSession(req *types.GetSessionRequest) (resp *types.GetSessionResponse, err error) {
+	// 旧版按会话 id 翻页的游标无法对应按最近活动时间的排序
+	if req.Cursor != 0 {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "legacy cursor: %d", req.Cursor)
+	}
+	after, err := parseSessionCursor(req.After)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "after: %s", req.After)
+	}
 	userId := ctxdata.GetUidFromCtx(l.ctx)
-	sessions, err := l.svcCtx.SessionModel.FindByQuery(l.ctx, req.Cursor, req.PageSize, map[string]interface{}{"user_id": userId})
+	sessions, err := l.svcCtx.SessionModel.FindByUser(l.ctx, userId, model.SessionFilter{
+		CharacterId: req.CharacterId,
+		Archived:    req.Archived,
+	}, after, req.PageSize+1)
 	if err != nil {
 		return nil, err
 	}
-	return &types.GetSessionResponse{Sessions: castSessions(sessions, userId)}, nil
+	resp = &types.GetSessionResponse{}
+	if int64(len(sessions)) > req.PageSize {
+		sessions, resp.HasMore = sessions[:req.PageSize], true
+	}
+	if len(sessions) > 0 {
+		resp.NextCursor = formatSessionCursor(sessions[len(sessions)-1])
+	}
+	resp.Sessions = castSessions(sessions, userId)
+	return resp, nil
+}
+
+// formatSessionCursor 游标格式为 是否置顶_最近活动时间（unix 微秒）_会话 id
+func formatSessionCursor(session *model.Session) string {
+	return fmt.Sprintf("%d_%d_%d", session.IsPinned, session.UpdatedAt.UnixMicro(), session.Id)
+}
+
+// parseSessionCursor 解析 formatSessionCursor 生成的游标，空游标表示第一页
+func parseSessionCursor(cursor string) (*model.SessionCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	parts := strings.Split(cursor, "_")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid session cursor")
+	}
+	var values [3]int64
+	for i, part := range parts {
+		v, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return &model.SessionCursor{IsPinned: values[0], UpdatedAt: time.UnixMicro(values[1]), Id: values[2]}, nil
 }
 
 func castSessions(sessions []*model.Session, userId int64) []types.Session {
@@ -41,7 +93,10 @@ func castSessions(sessions []*model.Session, userId int64) []types.Session {
 			SessionId:   session.Id,
 			UserId:      userId,
 			CharacterId: session.CharacterId,
+			PersonaId:   session.PersonaId,
 			Title:       session.Title,
+			IsArchived:  session.IsArchived == 1,
+			IsPinned:    session.IsPinned == 1,
 			CreatedAt:   session.CreatedAt.Unix(),
 			UpdatedAt:   session.UpdatedAt.Unix(),
 		})
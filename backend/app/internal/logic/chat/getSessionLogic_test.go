@@ -0,0 +1,81 @@
+package chat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+)
+
+func sessionIds(sessions []types.Session) []int64 {
+	var ids []int64
+	for _, s := range sessions {
+		ids = append(ids, s.SessionId)
+	}
+	return ids
+}
+
+func TestGetSessionPagesWhileSessionsAreTouched(t *testing.T) {
+	env := newTestEnv(t)
+	// 会话 1 置顶，2~5 的最近活动时间依次更早，4 与 5 相同时按 id 倒序
+	base := time.Now().Add(-time.Hour)
+	env.sessions.sessions[1].IsPinned = 1
+	env.sessions.sessions[1].UpdatedAt = base
+	for id, ago := range map[int64]time.Duration{2: 1, 3: 2, 4: 3, 5: 3} {
+		env.sessions.sessions[id] = &model.Session{Id: id, CharacterId: 10, UserId: 1, UpdatedAt: base.Add(-ago * time.Minute)}
+	}
+	logic := NewGetSessionLogic(userContext(1), env.svcCtx)
+
+	first, err := logic.GetSession(&types.GetSessionRequest{PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sessionIds(first.Sessions); len(got) != 2 || got[0] != 1 || got[1] != 2 || !first.HasMore {
+		t.Fatalf("first page = %v, has_more = %v", got, first.HasMore)
+	}
+	// 翻页期间会话 4 收到新消息，移到未置顶会话的最前
+	if err = env.sessions.Touch(context.Background(), nil, 4); err != nil {
+		t.Fatal(err)
+	}
+	second, err := logic.GetSession(&types.GetSessionRequest{After: first.NextCursor, PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sessionIds(second.Sessions); len(got) != 2 || got[0] != 3 || got[1] != 5 || second.HasMore {
+		t.Fatalf("second page = %v, has_more = %v, want [3 5] without more", got, second.HasMore)
+	}
+}
+
+func TestGetSessionRejectsInvalidCursor(t *testing.T) {
+	env := newTestEnv(t)
+	logic := NewGetSessionLogic(userContext(1), env.svcCtx)
+	for _, req := range []types.GetSessionRequest{
+		{After: "20", PageSize: 2},
+		// 旧版按会话 id 翻页的游标
+		{Cursor: 20, PageSize: 2},
+	} {
+		_, err := logic.GetSession(&req)
+		var codeErr *errorz.CodeError
+		if !errors.As(err, &codeErr) || codeErr.GetErrCode() != errorz.REUQEST_PARAM_ERROR {
+			t.Fatalf("req = %+v, err = %v, want REUQEST_PARAM_ERROR", req, err)
+		}
+	}
+}
+
+func TestGetSessionRequestValidatesPageSize(t *testing.T) {
+	validate := validator.New()
+	// pageSize 为 0 时会返回空页且 has_more 为 true，负数会使切片越界
+	for _, size := range []int64{-1, 0, 101} {
+		if err := validate.Struct(types.GetSessionRequest{PageSize: size}); err == nil {
+			t.Errorf("pageSize %d passed validation", size)
+		}
+	}
+	if err := validate.Struct(types.GetSessionRequest{PageSize: 20}); err != nil {
+		t.Fatal(err)
+	}
+}
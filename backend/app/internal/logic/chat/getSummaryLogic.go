@@ -0,0 +1,34 @@
+package chat
+
+import (
+	"context"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetSummaryLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetSummaryLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetSummaryLogic {
+	return &GetSummaryLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetSummaryLogic) GetSummary(req *types.GetSummaryRequest) (resp *types.GetSummaryResponse, err error) {
+	session, err := findOwnedSession(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &types.GetSummaryResponse{
+		Summary: session.Summary,
+	}, nil
+}
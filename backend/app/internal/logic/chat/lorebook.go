@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"qiniuyun/backend/model"
+	"strings"
+)
+
+const (
+	// loreScanDepth 检查最近多少条消息中是否出现世界书的触发关键词
+	loreScanDepth = 4
+
+	LorePositionBefore = "before"
+	LorePositionAfter  = "after"
+)
+
+// matchLorebook 返回被最近消息触发的世界书条目，关键词不区分大小写，保持 entries 的优先级顺序
+func matchLorebook(entries []*model.LorebookEntry, messages []*model.Message) []*model.LorebookEntry {
+	if len(messages) > loreScanDepth {
+		messages = messages[len(messages)-loreScanDepth:]
+	}
+	var text strings.Builder
+	for _, msg := range messages {
+		text.WriteString(strings.ToLower(msg.Content))
+		text.WriteByte('\n')
+	}
+	scanned := text.String()
+	var res []*model.LorebookEntry
+	for _, entry := range entries {
+		if entry.Enabled != 1 {
+			continue
+		}
+		for _, keyword := range entry.Keywords {
+			if keyword != "" && strings.Contains(scanned, strings.ToLower(keyword)) {
+				res = append(res, entry)
+				break
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,49 @@
+package chat
+
+import (
+	"slices"
+	"testing"
+
+	"qiniuyun/backend/model"
+)
+
+func TestMatchLorebook(t *testing.T) {
+	harbor := &model.LorebookEntry{Id: 1, Keywords: model.StringArray{"港口", "Harbor"}, Enabled: 1}
+	tower := &model.LorebookEntry{Id: 2, Keywords: model.StringArray{"灯塔"}, Enabled: 1}
+	market := &model.LorebookEntry{Id: 3, Keywords: model.StringArray{"集市"}, Enabled: 0}
+	empty := &model.LorebookEntry{Id: 4, Keywords: model.StringArray{""}, Enabled: 1}
+	entries := []*model.LorebookEntry{tower, harbor, market, empty}
+
+	tests := []struct {
+		name     string
+		messages []string
+		want     []int64
+	}{
+		{name: "no keyword", messages: []string{"今天天气不错"}, want: nil},
+		{name: "single keyword", messages: []string{"我们去港口吧"}, want: []int64{1}},
+		{name: "case folding", messages: []string{"Let's go to the HARBOR"}, want: []int64{1}},
+		{name: "keeps priority order", messages: []string{"港口边有座灯塔"}, want: []int64{2, 1}},
+		{name: "disabled entry skipped", messages: []string{"去集市"}, want: nil},
+		{name: "keyword spread across messages", messages: []string{"港口", "灯塔"}, want: []int64{2, 1}},
+		{
+			name:     "beyond scan depth",
+			messages: []string{"灯塔", "你好", "你好", "你好", "港口"},
+			want:     []int64{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []*model.Message
+			for _, content := range tt.messages {
+				messages = append(messages, &model.Message{Content: content})
+			}
+			var got []int64
+			for _, entry := range matchLorebook(entries, messages) {
+				got = append(got, entry.Id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("matchLorebook(%q) = %v, want %v", tt.messages, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/common/embedding"
+	"qiniuyun/backend/common/globalkey"
+	"qiniuyun/backend/common/job"
+	"qiniuyun/backend/model"
+	"strings"
+	"time"
+)
+
+const (
+	// memoryExtractInterval 当前分支上累计多少条未提取的消息后提取一次长期记忆
+	memoryExtractInterval = 10
+	// memoryExtractBatch 一次最多从多少条消息中提取，其余的在之后的任务中继续提取
+	memoryExtractBatch = 50
+)
+
+// JobExtractMemories 从会话中提取关于用户的长期记忆
+const JobExtractMemories = "chat.extract_memories"
+
+type sessionPayload struct {
+	SessionId int64 `json:"session_id"`
+}
+
+// extractMemories 每轮回复结束后调用，提交长期记忆提取任务，同一会话同时只有一个任务在排队或执行
+func (l *ChatLogic) extractMemories(sessionId int64) {
+	err := l.svcCtx.Jobs.Enqueue(context.Background(), JobExtractMemories, fmt.Sprintf("memory:%d", sessionId), sessionPayload{SessionId: sessionId})
+	if err != nil {
+		l.Errorf("enqueue extract memories of session %d: %+v", sessionId, err)
+	}
+}
+
+// HandleExtractMemories 当前分支上未提取的消息足够多时，用 LLM 从中提炼关于用户的事实，
+// 以该用户的私有记忆写入角色的向量集合，之后的对话会与角色自身的记忆一起被检索
+func HandleExtractMemories(svcCtx *svc.ServiceContext) job.Handler {
+	return func(ctx context.Context, j *job.Job) error {
+		var payload sessionPayload
+		if err := j.Bind(&payload); err != nil {
+			return err
+		}
+		return extractSessionMemories(ctx, svcCtx, payload.SessionId)
+	}
+}
+
+func extractSessionMemories(ctx context.Context, svcCtx *svc.ServiceContext, sessionId int64) error {
+	session, err := svcCtx.SessionModel.FindOne(ctx, sessionId)
+	if errors.Is(err, model.ErrNotFound) {
+		// 会话已删除
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// 只读取游标之后的消息，不随会话变长而变慢
+	pending, err := svcCtx.MessageModel.FindActiveRange(ctx, sessionId, session.MemoryCursor, 0, memoryExtractBatch)
+	if err != nil {
+		return err
+	}
+	if len(pending) < memoryExtractInterval {
+		return nil
+	}
+	character, err := svcCtx.CharacterModel.FindOneByUser(ctx, session.CharacterId, session.UserId)
+	if err != nil {
+		return err
+	}
+	memories, err := svcCtx.LLM.ExtractMemories(character.Name, formatDialogue(pending, character.Name))
+	if err != nil {
+		return err
+	}
+	if len(memories) > 0 {
+		vectors, err := svcCtx.Embedding.GetEmbeddings(ctx, memories)
+		if err != nil {
+			return err
+		}
+		// 提取期间会话可能已被删除，此时写入的记忆不会再被删除
+		if exists, err := sessionExists(ctx, svcCtx, session.Id); err != nil || !exists {
+			return err
+		}
+		err = svcCtx.Embedding.InsertVectors(ctx, globalkey.Collection(session.CharacterId), memories, vectors, embedding.Payload{
+			UserId:    session.UserId,
+			SessionId: session.Id,
+			Source:    embedding.SourceConversation,
+			CreatedAt: time.Now().Unix(),
+		})
+		if err != nil {
+			return err
+		}
+		// 写入的同时会话被删除时，补删本次写入的记忆
+		if exists, err := sessionExists(ctx, svcCtx, session.Id); err != nil {
+			return err
+		} else if !exists {
+			return svcCtx.Embedding.DeleteSession(ctx, globalkey.Collection(session.CharacterId), session.Id)
+		}
+	}
+	return svcCtx.SessionModel.UpdateColumns(ctx, nil, session.Id, map[string]interface{}{
+		"memory_cursor": pending[len(pending)-1].Id,
+	})
+}
+
+// sessionExists 会话是否仍未被删除
+func sessionExists(ctx context.Context, svcCtx *svc.ServiceContext, sessionId int64) (bool, error) {
+	_, err := svcCtx.SessionModel.FindOne(ctx, sessionId)
+	if errors.Is(err, model.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// formatDialogue 将消息整理为逐行的「说话人: 内容」，供 LLM 提取记忆与生成摘要
+func formatDialogue(messages []*model.Message, characterName string) string {
+	var dialogue strings.Builder
+	for _, msg := range messages {
+		speaker := "用户"
+		if msg.Role == RoleAssistant {
+			speaker = characterName
+		}
+		fmt.Fprintf(&dialogue, "%s: %s\n", speaker, msg.Content)
+	}
+	return dialogue.String()
+}
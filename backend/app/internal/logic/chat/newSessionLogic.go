@@ -2,8 +2,11 @@ package chat
 
 import (
 	"context"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/common/llm"
 	"qiniuyun/backend/model"
 
 	"qiniuyun/backend/app/internal/svc"
@@ -28,28 +31,44 @@ func NewNewSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NewSes
 
 func (l *NewSessionLogic) NewSession(req *types.NewSessionRequest) (resp *types.NewSessionResponse, err error) {
 	userId := ctxdata.GetUidFromCtx(l.ctx)
-	character, err := l.svcCtx.CharacterModel.FindOne(l.ctx, req.CharacterId)
+	// 私有角色只有创建者可以对话
+	character, err := l.svcCtx.CharacterModel.FindOneByUser(l.ctx, req.CharacterId, userId)
 	if err != nil {
 		return nil, err
 	}
-	opening, err := l.svcCtx.LLM.GenerateOpening(character.OpenLine)
-	if err != nil {
+	// 生成中的角色可以先对话，使用临时拼接的系统提示词；生成失败需重新生成后才能新建会话
+	if character.Status == model.CharacterStatusFailed {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.CHARACTER_NOT_READY_ERROR), "characterId: %d, reason: %s", character.Id, character.StatusReason)
+	}
+	if err = checkPersona(l.ctx, l.svcCtx, userId, req.PersonaId); err != nil {
 		return nil, err
 	}
 	session := model.Session{
 		CharacterId: req.CharacterId,
 		UserId:      userId,
-		Title:       getTitle(opening),
+		PersonaId:   req.PersonaId,
+	}
+	placeholders := llm.Placeholders{
+		User: resolvePersona(l.ctx, l.svcCtx, &session).name,
+		Char: character.Name,
+	}
+	opening, err := l.svcCtx.LLM.GenerateOpening(placeholders.Replace(character.OpenLine))
+	if err != nil {
+		return nil, err
 	}
+	opening = placeholders.Replace(opening)
+	session.Title = getTitle(opening)
 	err = l.svcCtx.MessageModel.Transaction(l.ctx, func(db *gorm.DB) error {
 		e := l.svcCtx.SessionModel.Insert(l.ctx, db, &session)
 		if e != nil {
 			return e
 		}
 		e = l.svcCtx.MessageModel.Insert(l.ctx, db, &model.Message{
-			SessionId: session.Id,
-			Role:      "assistant",
-			Content:   opening,
+			SessionId:  session.Id,
+			Role:       "assistant",
+			Content:    opening,
+			IsSelected: 1,
+			IsActive:   1,
 		})
 		return e
 	})
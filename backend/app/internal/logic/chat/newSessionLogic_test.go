@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/model"
+)
+
+func TestNewSessionGeneratesOpening(t *testing.T) {
+	env := newTestEnv(t, fakeReply{chunks: []string{"旅行者，", "欢迎来到雨季的港口。"}})
+	env.characters.characters[10].OpenLine = "你好"
+
+	resp, err := NewNewSessionLogic(userContext(1), env.svcCtx).NewSession(&types.NewSessionRequest{CharacterId: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := env.sessions.FindOne(context.Background(), resp.SessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const opening = "旅行者，欢迎来到雨季的港口。"
+	if session.UserId != 1 || session.CharacterId != 10 || session.Title != opening {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+	messages := env.messages.all()
+	if len(messages) != 1 {
+		t.Fatalf("messages = %d, want 1", len(messages))
+	}
+	if msg := messages[0]; msg.SessionId != session.Id || msg.Role != RoleAssistant || msg.Content != opening || msg.IsSelected != 1 {
+		t.Fatalf("unexpected opening message: %+v", msg)
+	}
+}
+
+func TestNewSessionRejectsPrivateCharacter(t *testing.T) {
+	env := newTestEnv(t)
+	env.characters.characters[10].IsPublic = 0
+
+	if _, err := NewNewSessionLogic(userContext(1), env.svcCtx).NewSession(&types.NewSessionRequest{CharacterId: 10}); err != model.ErrNotFound {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if len(env.messages.all()) != 0 {
+		t.Fatal("session created for a private character of another user")
+	}
+	// 创建者可以与自己的私有角色对话
+	if _, err := NewNewSessionLogic(userContext(2), env.svcCtx).NewSession(&types.NewSessionRequest{CharacterId: 10}); err != nil {
+		t.Fatal(err)
+	}
+}
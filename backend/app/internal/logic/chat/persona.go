@@ -0,0 +1,43 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/model"
+	"strings"
+)
+
+// persona 用户在会话中的身份，渲染进系统提示词，并用于替换 {{user}} 占位符
+type persona struct {
+	name        string
+	description string
+}
+
+// resolvePersona 返回会话使用的人设；未选择人设或人设已被删除时，使用用户的个人资料
+func resolvePersona(ctx context.Context, svcCtx *svc.ServiceContext, session *model.Session) persona {
+	if session.PersonaId != 0 {
+		p, err := svcCtx.PersonaModel.FindOne(ctx, session.PersonaId)
+		if err == nil && p.UserId == session.UserId {
+			return persona{name: p.Name, description: p.Description}
+		}
+	}
+	user, err := svcCtx.UserModel.FindOne(ctx, session.UserId)
+	if err != nil {
+		return persona{}
+	}
+	var profile []string
+	switch user.Sex.String {
+	case "male":
+		profile = append(profile, "性别：男")
+	case "female":
+		profile = append(profile, "性别：女")
+	}
+	if user.Birthday.Valid {
+		profile = append(profile, fmt.Sprintf("生日：%s", user.Birthday.Time.Format("2006-01-02")))
+	}
+	if user.Signature.Valid && user.Signature.String != "" {
+		profile = append(profile, fmt.Sprintf("个性签名：%s", user.Signature.String))
+	}
+	return persona{name: user.Name, description: strings.Join(profile, "\n")}
+}
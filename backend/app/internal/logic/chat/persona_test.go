@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/llm"
+	"qiniuyun/backend/model"
+)
+
+func TestResolvePersona(t *testing.T) {
+	env := newTestEnv(t)
+	env.personas.personas[5] = &model.Persona{Id: 5, UserId: 1, Name: "船长", Description: "一位老练的航海者"}
+	env.personas.personas[6] = &model.Persona{Id: 6, UserId: 2, Name: "他人的人设"}
+
+	tests := []struct {
+		name      string
+		personaId int64
+		want      string
+	}{
+		{"profile when no persona", 0, "小林"},
+		{"own persona", 5, "船长"},
+		{"profile when persona of another user", 6, "小林"},
+		{"profile when persona deleted", 7, "小林"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolvePersona(context.Background(), env.svcCtx, &model.Session{UserId: 1, PersonaId: tt.personaId})
+			if got.name != tt.want {
+				t.Fatalf("name = %q, want %q", got.name, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSessionReplacesPlaceholders(t *testing.T) {
+	env := newTestEnv(t, fakeReply{chunks: []string{"欢迎，{{user}}。我是{{char}}。"}})
+	env.personas.personas[5] = &model.Persona{Id: 5, UserId: 1, Name: "船长"}
+	env.personas.personas[6] = &model.Persona{Id: 6, UserId: 2, Name: "他人的人设"}
+
+	if _, err := NewNewSessionLogic(userContext(1), env.svcCtx).NewSession(&types.NewSessionRequest{CharacterId: 10, PersonaId: 6}); err == nil {
+		t.Fatal("session created with a persona of another user")
+	}
+	if _, err := NewNewSessionLogic(userContext(1), env.svcCtx).NewSession(&types.NewSessionRequest{CharacterId: 10, PersonaId: 5}); err != nil {
+		t.Fatal(err)
+	}
+	messages := env.messages.all()
+	if len(messages) != 1 || messages[0].Content != "欢迎，船长。我是艾拉。" {
+		t.Fatalf("unexpected opening: %+v", messages)
+	}
+}
+
+func TestContextBuilderRendersPersona(t *testing.T) {
+	env := newTestEnv(t)
+	got := newContextBuilder(env.svcCtx.Config.LLM).build(promptParts{
+		systemPrompt: "你是{{char}}，称呼对方为{{user}}。",
+		persona:      persona{name: "船长", description: "一位老练的航海者"},
+		placeholders: llm.Placeholders{User: "船长", Char: "艾拉"},
+	}, messagesOf("你好"))
+	system := got[0].Content
+	for _, want := range []string{"你是艾拉，称呼对方为船长。", "Name: 船长", "一位老练的航海者"} {
+		if !strings.Contains(system, want) {
+			t.Fatalf("system prompt %q does not contain %q", system, want)
+		}
+	}
+}
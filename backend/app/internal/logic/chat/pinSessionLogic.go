@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type PinSessionLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewPinSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *PinSessionLogic {
+	return &PinSessionLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// PinSession 置顶或取消置顶会话，置顶的会话排在会话列表最前
+func (l *PinSessionLogic) PinSession(req *types.PinSessionRequest) error {
+	session, err := findOwnedSession(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	err = l.svcCtx.SessionModel.UpdateColumns(l.ctx, nil, session.Id, map[string]interface{}{
+		"is_pinned": req.Pinned,
+	})
+	if err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "sessionId: %v,err: %+v", session.Id, err)
+	}
+	return nil
+}
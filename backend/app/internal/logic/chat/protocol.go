@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"encoding/binary"
+	"github.com/gorilla/websocket"
+	"sync"
+)
+
+// BinaryAudioProtocol 客户端在 Sec-WebSocket-Protocol 中声明该子协议后，音频改用二进制帧发送，
+// 文本事件仍为 JSON；未声明时保持原有的 JSON(base64) 音频
+const BinaryAudioProtocol = "roletalk.binary-audio"
+
+// 二进制音频帧头部，12 字节，大端序：
+//
+//	[0]    版本号
+//	[1]    编码，见 audioCodecs
+//	[2]    标志位，bit0 表示本次回复的最后一帧
+//	[3]    保留
+//	[4:8]  消息编号，同一连接内每次语音回复递增
+//	[8:12] 序号，从 1 开始
+const (
+	audioFrameVersion   = 1
+	audioFrameHeaderLen = 12
+	audioFlagFinal      = 1 << 0
+)
+
+// wsConn 为写操作加锁，允许语音识别、语音合成等协程与主流程同时向客户端推送
+type wsConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+func (c *wsConn) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
+func (c *wsConn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Conn.WriteMessage(messageType, data)
+}
+
+var audioCodecs = map[string]byte{
+	"mp3":  1,
+	"pcm":  2,
+	"opus": 3,
+}
+
+// audioFrame 一段待推送给客户端的音频
+type audioFrame struct {
+	MessageId uint32
+	Seq       uint32
+	Encoding  string
+	Final     bool
+	Audio     []byte
+}
+
+func encodeAudioFrame(frame audioFrame) []byte {
+	buf := make([]byte, audioFrameHeaderLen+len(frame.Audio))
+	buf[0] = audioFrameVersion
+	buf[1] = audioCodecs[frame.Encoding]
+	if frame.Final {
+		buf[2] |= audioFlagFinal
+	}
+	binary.BigEndian.PutUint32(buf[4:8], frame.MessageId)
+	binary.BigEndian.PutUint32(buf[8:12], frame.Seq)
+	copy(buf[audioFrameHeaderLen:], frame.Audio)
+	return buf
+}
+
+// writeAudio 按连接协商的协议发送音频；JSON 模式下结束帧由 done 消息表示，不单独发送
+func writeAudio(conn *wsConn, frame audioFrame) error {
+	if conn.Subprotocol() == BinaryAudioProtocol {
+		return conn.WriteMessage(websocket.BinaryMessage, encodeAudioFrame(frame))
+	}
+	if len(frame.Audio) == 0 {
+		return nil
+	}
+	return conn.WriteJSON(wsResponse{
+		Type:     WSMessageResponseTypeAudio,
+		Audio:    frame.Audio,
+		Encoding: frame.Encoding,
+		Seq:      int(frame.Seq),
+	})
+}
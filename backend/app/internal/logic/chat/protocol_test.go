@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeAudioFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame audioFrame
+		want  []byte
+	}{
+		{
+			name:  "mp3 chunk",
+			frame: audioFrame{MessageId: 1, Seq: 2, Encoding: "mp3", Audio: []byte{0xff, 0xfb}},
+			want:  []byte{1, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 2, 0xff, 0xfb},
+		},
+		{
+			name:  "final frame without audio",
+			frame: audioFrame{MessageId: 3, Seq: 7, Encoding: "opus", Final: true},
+			want:  []byte{1, 3, 1, 0, 0, 0, 0, 3, 0, 0, 0, 7},
+		},
+		{
+			name:  "big endian ids",
+			frame: audioFrame{MessageId: 0x01020304, Seq: 0x0a0b0c0d, Encoding: "pcm", Audio: []byte{9}},
+			want:  []byte{1, 2, 0, 0, 1, 2, 3, 4, 0x0a, 0x0b, 0x0c, 0x0d, 9},
+		},
+		{
+			name:  "unknown encoding",
+			frame: audioFrame{MessageId: 1, Seq: 1, Encoding: "wav"},
+			want:  []byte{1, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeAudioFrame(tt.frame)
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("encodeAudioFrame(%+v) = %v, want %v", tt.frame, got, tt.want)
+			}
+			if len(got) != audioFrameHeaderLen+len(tt.frame.Audio) {
+				t.Fatalf("frame length = %d, want header %d + audio %d", len(got), audioFrameHeaderLen, len(tt.frame.Audio))
+			}
+		})
+	}
+}
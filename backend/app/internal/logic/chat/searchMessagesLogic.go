@@ -0,0 +1,127 @@
+package chat
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"html"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+	"strings"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// searchPageSize 每页搜索结果数的默认值
+	searchPageSize = 20
+	// snippetRadius 片段在第一个命中位置前后各保留的字数
+	snippetRadius = 30
+)
+
+type SearchMessagesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSearchMessagesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SearchMessagesLogic {
+	return &SearchMessagesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SearchMessages 基于消息内容的全文索引搜索当前用户所有会话当前分支上的消息，返回命中的消息、所在会话与角色以及高亮的片段
+func (l *SearchMessagesLogic) SearchMessages(req *types.SearchMessagesRequest) (resp *types.SearchMessagesResponse, err error) {
+	keyword := strings.TrimSpace(req.Q)
+	// 只含运算符的关键词清理后没有可搜索的词，只有单字的词无法使用全文索引，都不执行查询
+	terms := model.SearchTerms(keyword)
+	if len(terms) == 0 || !model.HasIndexedTerm(keyword) {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "no indexed term in keyword: %q", req.Q)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = searchPageSize
+	}
+	userId := ctxdata.GetUidFromCtx(l.ctx)
+	hits, err := l.svcCtx.MessageModel.Search(l.ctx, userId, keyword, req.Cursor, limit+1)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "userId: %v, keyword: %s, err: %+v", userId, keyword, err)
+	}
+	resp = &types.SearchMessagesResponse{
+		Hits: make([]types.MessageHit, 0, len(hits)),
+	}
+	if len(hits) > limit {
+		hits, resp.HasMore = hits[:limit], true
+	}
+	for _, hit := range hits {
+		resp.Hits = append(resp.Hits, castMessageHit(hit, terms))
+	}
+	if len(hits) > 0 {
+		resp.Cursor = hits[len(hits)-1].Id
+	}
+	return resp, nil
+}
+
+func castMessageHit(hit *model.MessageHit, terms []string) types.MessageHit {
+	return types.MessageHit{
+		Message:       castMessages([]*model.Message{&hit.Message})[0],
+		SessionTitle:  hit.SessionTitle,
+		CharacterId:   hit.CharacterId,
+		CharacterName: hit.CharacterName,
+		Snippet:       highlight(hit.Content, terms),
+	}
+}
+
+// highlight 截取第一个命中位置前后 snippetRadius 字作为片段，片段中的关键词不区分大小写地以 <em> 标记
+func highlight(content string, terms []string) string {
+	text := []rune(content)
+	lower := []rune(strings.ToLower(content))
+	if len(lower) != len(text) {
+		lower = text
+	}
+	needles := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		needles = append(needles, []rune(strings.ToLower(term)))
+	}
+	// match 返回从 i 开始命中的关键词长度，未命中时为 0
+	match := func(i int) int {
+		for _, needle := range needles {
+			if len(needle) > 0 && i+len(needle) <= len(lower) && string(lower[i:i+len(needle)]) == string(needle) {
+				return len(needle)
+			}
+		}
+		return 0
+	}
+
+	first := 0
+	for i := range lower {
+		if match(i) > 0 {
+			first = i
+			break
+		}
+	}
+	start, end := max(first-snippetRadius, 0), min(first+snippetRadius, len(text))
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+	for i := start; i < end; {
+		if n := match(i); n > 0 {
+			snippet.WriteString("<em>" + html.EscapeString(string(text[i:i+n])) + "</em>")
+			i += n
+			continue
+		}
+		snippet.WriteString(html.EscapeString(string(text[i])))
+		i++
+	}
+	if end < len(text) {
+		snippet.WriteString("…")
+	}
+	return snippet.String()
+}
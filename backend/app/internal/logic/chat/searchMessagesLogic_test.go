@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+)
+
+// searchMessageModel 按 id 倒序返回固定的命中结果
+type searchMessageModel struct {
+	model.MessageModel
+	hits  []*model.MessageHit
+	calls int
+}
+
+func (m *searchMessageModel) Search(ctx context.Context, userId int64, keyword string, cursor int64, limit int) ([]*model.MessageHit, error) {
+	m.calls++
+	var res []*model.MessageHit
+	for _, hit := range m.hits {
+		if (cursor == 0 || hit.Id < cursor) && len(res) < limit {
+			res = append(res, hit)
+		}
+	}
+	return res, nil
+}
+
+func TestSearchMessagesPaginates(t *testing.T) {
+	env := newTestEnv(t)
+	var hits []*model.MessageHit
+	for id := int64(5); id > 0; id-- {
+		hits = append(hits, &model.MessageHit{
+			Message:       model.Message{Id: id, SessionId: 1, Role: RoleUser, Content: "你好"},
+			CharacterName: "艾拉",
+		})
+	}
+	env.svcCtx.MessageModel = &searchMessageModel{hits: hits}
+	logic := NewSearchMessagesLogic(userContext(1), env.svcCtx)
+
+	resp, err := logic.SearchMessages(&types.SearchMessagesRequest{Q: "你好", Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Hits) != 3 || !resp.HasMore || resp.Cursor != 3 {
+		t.Fatalf("first page = %d hits, has_more = %v, cursor = %d", len(resp.Hits), resp.HasMore, resp.Cursor)
+	}
+	resp, err = logic.SearchMessages(&types.SearchMessagesRequest{Q: "你好", Cursor: resp.Cursor, Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Hits) != 2 || resp.HasMore || resp.Hits[0].Message.Id != 2 {
+		t.Fatalf("second page = %+v", resp)
+	}
+	if resp.Hits[0].CharacterName != "艾拉" || resp.Hits[0].Snippet != "<em>你好</em>" {
+		t.Fatalf("hit = %+v", resp.Hits[0])
+	}
+}
+
+func TestSearchMessagesRejectsKeywordWithoutIndexedTerm(t *testing.T) {
+	env := newTestEnv(t)
+	messages := &searchMessageModel{}
+	env.svcCtx.MessageModel = messages
+	logic := NewSearchMessagesLogic(userContext(1), env.svcCtx)
+
+	// 去掉运算符后没有可搜索的词，不能变成不带条件的查询；只有单字的词时无法使用全文索引
+	for _, q := range []string{`* + " @`, "你 a"} {
+		_, err := logic.SearchMessages(&types.SearchMessagesRequest{Q: q})
+		var codeErr *errorz.CodeError
+		if !errors.As(err, &codeErr) || codeErr.GetErrCode() != errorz.REUQEST_PARAM_ERROR {
+			t.Fatalf("q = %q, err = %v, want REUQEST_PARAM_ERROR", q, err)
+		}
+	}
+	if messages.calls != 0 {
+		t.Fatalf("Search called %d times", messages.calls)
+	}
+}
+
+func TestSearchMessagesHighlightsCleanedTerms(t *testing.T) {
+	env := newTestEnv(t)
+	env.svcCtx.MessageModel = &searchMessageModel{hits: []*model.MessageHit{
+		{Message: model.Message{Id: 1, SessionId: 1, Role: RoleUser, Content: "我想吃面包和牛奶"}},
+	}}
+	logic := NewSearchMessagesLogic(userContext(1), env.svcCtx)
+
+	resp, err := logic.SearchMessages(&types.SearchMessagesRequest{Q: `+面包 "牛奶"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Hits) != 1 || resp.Hits[0].Snippet != "我想吃<em>面包</em>和<em>牛奶</em>" {
+		t.Fatalf("hits = %+v", resp.Hits)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	// 片段从命中位置前 snippetRadius 字开始，到命中位置后 snippetRadius 字结束
+	long := strings.Repeat("啊", 40) + "老奶奶" + strings.Repeat("呀", 40)
+	tests := []struct {
+		name    string
+		content string
+		terms   []string
+		want    string
+	}{
+		{"case insensitive", "I like BREAD and bread", []string{"bread"}, "I like <em>BREAD</em> and <em>bread</em>"},
+		{"multiple terms", "今天天气很好，适合散步", []string{"天气", "散步"}, "今天<em>天气</em>很好，适合<em>散步</em>"},
+		{"escape html", "<b>你好</b>", []string{"你好"}, "&lt;b&gt;<em>你好</em>&lt;/b&gt;"},
+		{"no match", "你好", []string{"再见"}, "你好"},
+		{"truncate", long, []string{"老奶奶"}, "…" + strings.Repeat("啊", 30) + "<em>老奶奶</em>" + strings.Repeat("呀", 27) + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.content, tt.terms); got != tt.want {
+				t.Errorf("highlight() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
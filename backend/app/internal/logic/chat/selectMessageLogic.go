@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SelectMessageLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSelectMessageLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SelectMessageLogic {
+	return &SelectMessageLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SelectMessage 切换到指定消息所在的分支：从该消息向上逐级设为父消息下被选中的子消息，
+// 直到与当前分支的分叉处，由 SelectChild 在分叉处切换当前分支
+func (l *SelectMessageLogic) SelectMessage(req *types.SelectMessageRequest) error {
+	msg, err := findOwnedMessage(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	err = l.svcCtx.MessageModel.Transaction(l.ctx, func(db *gorm.DB) error {
+		for node := msg; ; {
+			if e := l.svcCtx.MessageModel.SelectChild(l.ctx, db, node.SessionId, node.ParentId, node.Id); e != nil {
+				return e
+			}
+			if node.ParentId == 0 {
+				return nil
+			}
+			parent, e := l.svcCtx.MessageModel.FindOne(l.ctx, node.ParentId)
+			if e != nil {
+				return e
+			}
+			if parent.IsActive == 1 {
+				return nil
+			}
+			node = parent
+		}
+	})
+	if err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "messageId: %v,err: %+v", msg.Id, err)
+	}
+	return nil
+}
+
+// findOwnedMessage 查询消息并校验其所属会话属于当前用户
+func findOwnedMessage(ctx context.Context, svcCtx *svc.ServiceContext, id int64) (*model.Message, error) {
+	msg, err := svcCtx.MessageModel.FindOne(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "messageId: %v,err: %+v", id, err)
+	}
+	if _, err := findOwnedSession(ctx, svcCtx, msg.SessionId); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// findOwnedSession 查找当前用户自己的会话
+func findOwnedSession(ctx context.Context, svcCtx *svc.ServiceContext, id int64) (*model.Session, error) {
+	userId := ctxdata.GetUidFromCtx(ctx)
+	session, err := svcCtx.SessionModel.FindOne(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "sessionId: %v,err: %+v", id, err)
+	}
+	if session.UserId != userId {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REQUEST_ROLE_ERROR), "userId: %d, sessionId: %d", userId, session.Id)
+	}
+	return session, nil
+}
@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/types"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+)
+
+func userContext(userId int64) context.Context {
+	return context.WithValue(context.Background(), ctxdata.CtxKeyJwtUserId, userId)
+}
+
+// insertBranches 写入在第一条用户消息处分叉的两个分支，第二个分支被选中，返回两个分支上的回复
+func insertBranches(env *testEnv) (first, second int64) {
+	ctx := context.Background()
+	var replies []int64
+	for _, content := range []string{"第一版", "第二版"} {
+		userMsg := &model.Message{SessionId: 1, Role: RoleUser, Content: "你好", IsSelected: 1}
+		_ = env.messages.Insert(ctx, nil, userMsg)
+		_ = env.messages.SelectChild(ctx, nil, 1, 0, userMsg.Id)
+		reply := &model.Message{SessionId: 1, ParentId: userMsg.Id, Role: RoleAssistant, Content: content, IsSelected: 1, IsActive: 1}
+		_ = env.messages.Insert(ctx, nil, reply)
+		replies = append(replies, reply.Id)
+	}
+	return replies[0], replies[1]
+}
+
+func TestSelectMessageSwitchesBranch(t *testing.T) {
+	env := newTestEnv(t)
+	first, _ := insertBranches(env)
+
+	if err := NewSelectMessageLogic(userContext(1), env.svcCtx).SelectMessage(&types.SelectMessageRequest{Id: first}); err != nil {
+		t.Fatal(err)
+	}
+	// 选中较深的消息时其祖先也一并切换
+	path, _ := env.messages.FindActivePath(context.Background(), 1)
+	if len(path) != 2 || path[1].Id != first || path[0].Id != path[1].ParentId {
+		t.Fatalf("active path = %+v, want the branch of message %d", path, first)
+	}
+}
+
+func TestSelectMessageRejectsOtherUser(t *testing.T) {
+	env := newTestEnv(t)
+	first, second := insertBranches(env)
+
+	err := NewSelectMessageLogic(userContext(2), env.svcCtx).SelectMessage(&types.SelectMessageRequest{Id: first})
+	var codeErr *errorz.CodeError
+	if !errors.As(err, &codeErr) || codeErr.GetErrCode() != errorz.REQUEST_ROLE_ERROR {
+		t.Fatalf("err = %v, want REQUEST_ROLE_ERROR", err)
+	}
+	if path, _ := env.messages.FindActivePath(context.Background(), 1); path[len(path)-1].Id != second {
+		t.Fatal("another user switched the branch")
+	}
+}
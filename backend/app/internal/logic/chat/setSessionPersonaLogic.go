@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SetSessionPersonaLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSetSessionPersonaLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SetSessionPersonaLogic {
+	return &SetSessionPersonaLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SetSessionPersona 切换会话使用的人设，从下一轮回复开始生效
+func (l *SetSessionPersonaLogic) SetSessionPersona(req *types.SetSessionPersonaRequest) error {
+	session, err := findOwnedSession(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	if err = checkPersona(l.ctx, l.svcCtx, session.UserId, req.PersonaId); err != nil {
+		return err
+	}
+	err = l.svcCtx.SessionModel.UpdateColumns(l.ctx, nil, session.Id, map[string]interface{}{
+		"persona_id": req.PersonaId,
+	})
+	if err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "sessionId: %v,err: %+v", session.Id, err)
+	}
+	return nil
+}
+
+// checkPersona 人设可以为 0（使用个人资料），否则必须是该用户自己的人设
+func checkPersona(ctx context.Context, svcCtx *svc.ServiceContext, userId, personaId int64) error {
+	if personaId == 0 {
+		return nil
+	}
+	persona, err := svcCtx.PersonaModel.FindOne(ctx, personaId)
+	if err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "personaId: %v,err: %+v", personaId, err)
+	}
+	if persona.UserId != userId {
+		return errors.Wrapf(errorz.NewErrCode(errorz.REQUEST_ROLE_ERROR), "userId: %d, personaId: %d", userId, personaId)
+	}
+	return nil
+}
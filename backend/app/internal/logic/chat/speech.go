@@ -0,0 +1,133 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/zeromicro/go-zero/core/logx"
+	"log"
+	"qiniuyun/backend/common/auth"
+	"qiniuyun/backend/common/stt"
+	"strings"
+	"sync"
+)
+
+// readRequests 在独立协程中读取客户端消息，使生成回复期间仍能收到 stop 请求与语音数据。
+// speech_start 与 speech_end 之间的二进制帧为 16k PCM 音频，识别出的最终文本作为 voice 请求交给主流程
+func (l *ChatLogic) readRequests(conn *wsConn, canceler *replyCanceler) <-chan auth.WSRequest {
+	requests := make(chan auth.WSRequest, maxPendingRequests)
+	go func() {
+		var (
+			speech *speechSession
+			wg     sync.WaitGroup
+		)
+		defer func() {
+			if speech != nil {
+				speech.abort()
+			}
+			wg.Wait()
+			close(requests)
+		}()
+		for {
+			messageType, content, err := conn.ReadMessage()
+			if err != nil {
+				log.Println("read:", err)
+				canceler.cancel(errDisconnected)
+				return
+			}
+			if messageType == websocket.BinaryMessage {
+				if speech != nil {
+					speech.write(content)
+				}
+				continue
+			}
+			var data auth.WSRequest
+			if err := json.Unmarshal(content, &data); err != nil {
+				logx.Error(err)
+				continue
+			}
+			switch data.Type {
+			case WSMessageRequestTypeStop:
+				canceler.cancel(errStopped)
+			case WSMessageRequestTypeSpeechStart:
+				if speech != nil {
+					speech.abort()
+				}
+				speech, err = l.startSpeech(conn, requests, &wg)
+				if err != nil {
+					logx.Error(err)
+				}
+			case WSMessageRequestTypeSpeechEnd:
+				if speech != nil {
+					speech.finish()
+					speech = nil
+				}
+			default:
+				enqueue(requests, data)
+			}
+		}
+	}()
+	return requests
+}
+
+// enqueue 把请求交给主流程。读协程不能阻塞，否则收不到 stop 与断开；积压的请求过多时丢弃新的请求
+func enqueue(requests chan<- auth.WSRequest, data auth.WSRequest) {
+	select {
+	case requests <- data:
+	default:
+		logx.Errorf("too many pending requests, drop %s request", data.Type)
+	}
+}
+
+// speechSession 一次服务端语音识别
+type speechSession struct {
+	stream stt.Stream
+	cancel context.CancelFunc
+}
+
+// startSpeech 开始识别，并在后台把中间结果与最终结果以 transcript 消息推送给客户端
+func (l *ChatLogic) startSpeech(conn *wsConn, requests chan<- auth.WSRequest, wg *sync.WaitGroup) (*speechSession, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := l.svcCtx.STT.Start(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		for res := range stream.Results() {
+			if err := conn.WriteJSON(wsResponse{
+				Type:    WSMessageResponseTypeTranscript,
+				Content: res.Text,
+				Final:   res.Final,
+			}); err != nil {
+				logx.Error(err)
+			}
+			if res.Final && strings.TrimSpace(res.Text) != "" {
+				enqueue(requests, auth.WSRequest{Type: WSMessageRequestTypeVoice, Content: res.Text})
+			}
+		}
+	}()
+	return &speechSession{stream: stream, cancel: cancel}, nil
+}
+
+func (s *speechSession) write(pcm []byte) {
+	if err := s.stream.Write(pcm); err != nil {
+		logx.Error(err)
+	}
+}
+
+// finish 音频发送完毕，等待识别服务返回最终结果
+func (s *speechSession) finish() {
+	if err := s.stream.Close(); err != nil {
+		logx.Error(err)
+		s.cancel()
+	}
+}
+
+// abort 放弃本次识别，不再产生 voice 请求
+func (s *speechSession) abort() {
+	s.cancel()
+}
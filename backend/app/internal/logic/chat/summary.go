@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/common/job"
+	"qiniuyun/backend/model"
+	"slices"
+)
+
+const (
+	// summaryInterval 被挤出上下文的消息中累计多少条未摘要的消息后更新一次摘要
+	summaryInterval = 10
+	// summaryBatch 一次最多将多少条消息并入摘要，其余的在之后的任务中继续并入
+	summaryBatch = 100
+)
+
+// JobSummarize 将会话中被挤出上下文的消息并入剧情摘要
+const JobSummarize = "chat.summarize"
+
+// summarize 每轮回复结束后调用，提交剧情摘要任务，同一会话同时只有一个任务在排队或执行
+func (l *ChatLogic) summarize(sessionId int64) {
+	err := l.svcCtx.Jobs.Enqueue(context.Background(), JobSummarize, fmt.Sprintf("summary:%d", sessionId), sessionPayload{SessionId: sessionId})
+	if err != nil {
+		l.Errorf("enqueue summarize session %d: %+v", sessionId, err)
+	}
+}
+
+// HandleSummarize 当前分支上被挤出上下文、尚未摘要的消息足够多时，让 LLM 把它们并入会话的剧情摘要
+func HandleSummarize(svcCtx *svc.ServiceContext) job.Handler {
+	return func(ctx context.Context, j *job.Job) error {
+		var payload sessionPayload
+		if err := j.Bind(&payload); err != nil {
+			return err
+		}
+		return summarizeSession(ctx, svcCtx, payload.SessionId)
+	}
+}
+
+func summarizeSession(ctx context.Context, svcCtx *svc.ServiceContext, sessionId int64) error {
+	session, err := svcCtx.SessionModel.FindOne(ctx, sessionId)
+	if errors.Is(err, model.ErrNotFound) {
+		// 会话已删除
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	character, err := svcCtx.CharacterModel.FindOneByUser(ctx, session.CharacterId, session.UserId)
+	if err != nil {
+		return err
+	}
+	// 与回复时一样只在最近的 historyWindow 条消息中组装上下文，早于其中第一条放进上下文的消息都需要摘要
+	recent, err := svcCtx.MessageModel.FindPage(ctx, sessionId, 0, 0, historyWindow)
+	if err != nil || len(recent) == 0 {
+		return err
+	}
+	boundary := recent[newContextBuilder(svcCtx.Config.LLM).historyStart(characterPrompt(character), recent)].Id
+	previous, after := session.Summary, session.SummaryCursor
+	if after != 0 {
+		msg, err := svcCtx.MessageModel.FindOne(ctx, after)
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			return err
+		}
+		if err != nil || msg.IsActive != 1 {
+			// 摘要属于另一条分支，从当前分支的开头重新生成
+			previous, after = "", 0
+		}
+	}
+	pending, err := svcCtx.MessageModel.FindActiveRange(ctx, sessionId, after, boundary, summaryBatch)
+	if err != nil {
+		return err
+	}
+	if len(pending) < summaryInterval {
+		return nil
+	}
+	summary, err := svcCtx.LLM.Summarize(character.Name, previous, formatDialogue(pending, character.Name))
+	if err != nil {
+		return err
+	}
+	return svcCtx.SessionModel.UpdateColumns(ctx, nil, sessionId, map[string]interface{}{
+		"summary":        summary,
+		"summary_cursor": pending[len(pending)-1].Id,
+	})
+}
+
+// storySoFar 返回适用于 history 的剧情摘要，以及 history 中摘要之后的消息。history 为当前分支上最近的一段消息，
+// 摘要覆盖到的消息早于 history 且仍在当前分支上时 history 都在摘要之后。
+// 摘要覆盖的消息不在 history 所在分支上时不使用摘要，history 原样返回
+func storySoFar(ctx context.Context, svcCtx *svc.ServiceContext, session *model.Session, history []*model.Message) (string, []*model.Message) {
+	if session.Summary == "" {
+		return "", history
+	}
+	if session.SummaryCursor == 0 {
+		return session.Summary, history
+	}
+	idx := slices.IndexFunc(history, func(msg *model.Message) bool {
+		return msg.Id == session.SummaryCursor
+	})
+	if idx < 0 {
+		if session.SummaryCursor < history[0].Id {
+			if msg, err := svcCtx.MessageModel.FindOne(ctx, session.SummaryCursor); err == nil && msg.IsActive == 1 {
+				return session.Summary, history
+			}
+		}
+		return "", history
+	}
+	// 至少保留最新一条消息
+	return session.Summary, history[min(idx+1, len(history)-1):]
+}
@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/common/job"
+	"qiniuyun/backend/model"
+	"strings"
+)
+
+const (
+	// titleExchanges 当前分支上用户发送多少条消息后根据对话生成标题
+	titleExchanges = 3
+	// maxTitleLength 会话标题的最大字数
+	maxTitleLength = 30
+	// titleWindow 生成标题时读取的当前分支开头的消息数：开场白之后用户与角色交替发言
+	titleWindow = 2*titleExchanges + 1
+)
+
+// JobGenerateTitle 根据对话的开头生成会话标题
+const JobGenerateTitle = "chat.generate_title"
+
+// generateTitle 每轮回复结束后调用，会话标题仍为截取的开场白时提交标题生成任务。
+// 标题可能在连接期间被生成或修改，每次都重新读取会话
+func (l *ChatLogic) generateTitle(sessionId int64) {
+	session, err := l.svcCtx.SessionModel.FindOne(context.Background(), sessionId)
+	if err != nil {
+		if !errors.Is(err, model.ErrNotFound) {
+			l.Errorf("find session %d: %+v", sessionId, err)
+		}
+		return
+	}
+	if session.TitleSource != model.SessionTitleOpening {
+		return
+	}
+	err = l.svcCtx.Jobs.Enqueue(context.Background(), JobGenerateTitle, fmt.Sprintf("title:%d", sessionId), sessionPayload{SessionId: sessionId})
+	if err != nil {
+		l.Errorf("enqueue generate title of session %d: %+v", sessionId, err)
+	}
+}
+
+// HandleGenerateTitle 当前分支上的对话足够多时，让 LLM 根据对话的开头生成会话标题。
+// 标题只生成一次，用户修改过的标题不会被覆盖
+func HandleGenerateTitle(svcCtx *svc.ServiceContext) job.Handler {
+	return func(ctx context.Context, j *job.Job) error {
+		var payload sessionPayload
+		if err := j.Bind(&payload); err != nil {
+			return err
+		}
+		return generateSessionTitle(ctx, svcCtx, payload.SessionId)
+	}
+}
+
+func generateSessionTitle(ctx context.Context, svcCtx *svc.ServiceContext, sessionId int64) error {
+	session, err := svcCtx.SessionModel.FindOne(ctx, sessionId)
+	if errors.Is(err, model.ErrNotFound) {
+		// 会话已删除
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if session.TitleSource != model.SessionTitleOpening {
+		return nil
+	}
+	path, err := svcCtx.MessageModel.FindActiveRange(ctx, sessionId, 0, 0, titleWindow)
+	if err != nil {
+		return err
+	}
+	// 取到第 titleExchanges 条用户消息的回复为止
+	exchanges, end := 0, len(path)
+	for i, msg := range path {
+		if msg.Role != RoleUser {
+			continue
+		}
+		if exchanges++; exchanges == titleExchanges {
+			end = min(i+2, len(path))
+			break
+		}
+	}
+	if exchanges < titleExchanges {
+		return nil
+	}
+	character, err := svcCtx.CharacterModel.FindOneByUser(ctx, session.CharacterId, session.UserId)
+	if err != nil {
+		return err
+	}
+	title, err := svcCtx.LLM.GenerateTitle(character.Name, formatDialogue(path[:end], character.Name))
+	if err != nil {
+		return err
+	}
+	title = cleanTitle(title)
+	if title == "" {
+		return fmt.Errorf("empty title for session %d", sessionId)
+	}
+	return svcCtx.SessionModel.UpdateGeneratedTitle(ctx, sessionId, title)
+}
+
+// cleanTitle 去掉 LLM 输出的标题两端的空白、引号与句末标点，超出长度时截断
+func cleanTitle(title string) string {
+	for {
+		trimmed := strings.TrimRight(strings.Trim(strings.TrimSpace(title), "\"'“”‘’「」《》"), "。.！!？?")
+		if trimmed == title {
+			break
+		}
+		title = trimmed
+	}
+	if r := []rune(title); len(r) > maxTitleLength {
+		title = string(r[:maxTitleLength])
+	}
+	return title
+}
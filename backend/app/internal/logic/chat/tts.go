@@ -0,0 +1,137 @@
+package chat
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"qiniuyun/backend/common/tts"
+	"qiniuyun/backend/model"
+	"strings"
+	"unicode"
+)
+
+const (
+	// 句末标点，遇到即切分
+	sentenceEnds = "。！？!?；;…\n"
+	// 分句标点，片段足够长时才切分，避免合成过碎的音频
+	clauseEnds     = "，,、：:"
+	minClauseRunes = 12
+
+	maxPendingSentences = 64
+	maxConcurrentTTS    = 4
+)
+
+// ttsStream 将流式回复按句切分，每句并发请求 TTS，并按句子顺序把音频推送给客户端
+type ttsStream struct {
+	ctx       context.Context
+	conn      *wsConn
+	client    *tts.Client
+	messageId uint32
+	params    tts.Params
+	buf       string
+	sem       chan struct{}
+	pending   chan chan []byte
+	done      chan struct{}
+}
+
+func newTTSStream(ctx context.Context, conn *wsConn, client *tts.Client, messageId uint32, params tts.Params) *ttsStream {
+	t := &ttsStream{
+		ctx:       ctx,
+		conn:      conn,
+		client:    client,
+		messageId: messageId,
+		params:    params,
+		sem:       make(chan struct{}, maxConcurrentTTS),
+		pending:   make(chan chan []byte, maxPendingSentences),
+		done:      make(chan struct{}),
+	}
+	go t.send()
+	return t
+}
+
+// Write 追加 LLM 增量输出，凑成完整的句子后立即开始合成
+func (t *ttsStream) Write(delta string) {
+	var sentences []string
+	sentences, t.buf = splitSentences(t.buf + delta)
+	for _, sentence := range sentences {
+		t.synthesize(sentence)
+	}
+}
+
+// Close 合成剩余的文本，并等待所有音频发送完毕
+func (t *ttsStream) Close() {
+	t.synthesize(t.buf)
+	t.buf = ""
+	close(t.pending)
+	<-t.done
+}
+
+func (t *ttsStream) synthesize(text string) {
+	if !strings.ContainsFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		return
+	}
+	res := make(chan []byte, 1)
+	t.pending <- res
+	go func() {
+		t.sem <- struct{}{}
+		defer func() { <-t.sem }()
+		audio, err := t.client.Synthesize(t.ctx, t.params, text)
+		if err != nil {
+			logx.Error(err)
+		}
+		res <- audio
+	}()
+}
+
+// send 按入队顺序等待每句的合成结果并带序号（从1开始）推送，最后发送结束帧；
+// 回复被取消后只消费结果不再发送
+func (t *ttsStream) send() {
+	defer close(t.done)
+	frame := audioFrame{MessageId: t.messageId, Seq: 1, Encoding: t.params.EncodingOrDefault()}
+	for res := range t.pending {
+		frame.Audio = <-res
+		if len(frame.Audio) == 0 || t.ctx.Err() != nil {
+			continue
+		}
+		if err := writeAudio(t.conn, frame); err != nil {
+			logx.Error(err)
+		}
+		frame.Seq++
+	}
+	if t.ctx.Err() != nil {
+		return
+	}
+	frame.Audio, frame.Final = nil, true
+	if err := writeAudio(t.conn, frame); err != nil {
+		logx.Error(err)
+	}
+}
+
+// splitSentences 从 text 中切出完整的句子或足够长的分句，返回剩余未成句的部分
+func splitSentences(text string) (sentences []string, rest string) {
+	runes := []rune(text)
+	start := 0
+	for i, r := range runes {
+		switch {
+		case strings.ContainsRune(sentenceEnds, r):
+		case strings.ContainsRune(clauseEnds, r) && i+1-start >= minClauseRunes:
+		case r == '.' && i+1 < len(runes) && unicode.IsSpace(runes[i+1]):
+		default:
+			continue
+		}
+		sentences = append(sentences, string(runes[start:i+1]))
+		start = i + 1
+	}
+	return sentences, string(runes[start:])
+}
+
+// ttsParams 角色的音色与语音合成参数
+func ttsParams(character *model.Character) tts.Params {
+	return tts.Params{
+		VoiceType:   character.Voice,
+		Encoding:    character.TTSConfig.Encoding,
+		SpeedRatio:  character.TTSConfig.SpeedRatio,
+		PitchRatio:  character.TTSConfig.PitchRatio,
+		VolumeRatio: character.TTSConfig.VolumeRatio,
+		Emotion:     character.TTSConfig.Emotion,
+	}
+}
@@ -0,0 +1,33 @@
+package chat
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		sentences []string
+		rest      string
+	}{
+		{name: "empty", text: "", rest: ""},
+		{name: "unfinished", text: "今天下雨", rest: "今天下雨"},
+		{name: "sentence ends", text: "你好。今天下雨了！要带伞吗？还", sentences: []string{"你好。", "今天下雨了！", "要带伞吗？"}, rest: "还"},
+		{name: "newline", text: "第一行\n第二行", sentences: []string{"第一行\n"}, rest: "第二行"},
+		{name: "short clause kept", text: "好的，我们走吧", rest: "好的，我们走吧"},
+		{name: "long clause split", text: "旅行者今天一整天都在城里闲逛，终于", sentences: []string{"旅行者今天一整天都在城里闲逛，"}, rest: "终于"},
+		{name: "english period", text: "Hello there. How", sentences: []string{"Hello there."}, rest: " How"},
+		{name: "decimal point", text: "价格是3.5元", rest: "价格是3.5元"},
+		{name: "ellipsis", text: "嗯…好吧", sentences: []string{"嗯…"}, rest: "好吧"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentences, rest := splitSentences(tt.text)
+			if !slices.Equal(sentences, tt.sentences) || rest != tt.rest {
+				t.Fatalf("splitSentences(%q) = %q, %q, want %q, %q", tt.text, sentences, rest, tt.sentences, tt.rest)
+			}
+		})
+	}
+}
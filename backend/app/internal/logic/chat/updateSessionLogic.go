@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+	"strings"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type UpdateSessionLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUpdateSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UpdateSessionLogic {
+	return &UpdateSessionLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// UpdateSession 修改会话标题，用户修改过的标题不会再被自动生成的标题覆盖
+func (l *UpdateSessionLogic) UpdateSession(req *types.UpdateSessionRequest) error {
+	session, err := findOwnedSession(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "empty title, sessionId: %d", session.Id)
+	}
+	err = l.svcCtx.SessionModel.UpdateColumns(l.ctx, nil, session.Id, map[string]interface{}{
+		"title":        title,
+		"title_source": model.SessionTitleManual,
+	})
+	if err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "sessionId: %v,err: %+v", session.Id, err)
+	}
+	return nil
+}
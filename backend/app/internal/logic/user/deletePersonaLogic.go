@@ -0,0 +1,38 @@
+package user
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type DeletePersonaLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDeletePersonaLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DeletePersonaLogic {
+	return &DeletePersonaLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// DeletePersona 删除人设，正在使用它的会话改为使用个人资料
+func (l *DeletePersonaLogic) DeletePersona(req *types.DeletePersonaRequest) error {
+	persona, err := findOwnedPersona(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	if err = l.svcCtx.PersonaModel.Delete(l.ctx, nil, persona.Id); err != nil {
+		return errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "personaId: %v,err: %+v", req.Id, err)
+	}
+	return nil
+}
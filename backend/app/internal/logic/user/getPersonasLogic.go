@@ -0,0 +1,66 @@
+package user
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetPersonasLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetPersonasLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetPersonasLogic {
+	return &GetPersonasLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetPersonasLogic) GetPersonas() (resp *types.GetPersonasResponse, err error) {
+	userId := ctxdata.GetUidFromCtx(l.ctx)
+	personas, err := l.svcCtx.PersonaModel.FindByUser(l.ctx, userId)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "userId: %v,err: %+v", userId, err)
+	}
+	res := make([]types.Persona, 0, len(personas))
+	for _, persona := range personas {
+		res = append(res, castPersona(persona))
+	}
+	return &types.GetPersonasResponse{
+		Personas: res,
+	}, nil
+}
+
+func castPersona(persona *model.Persona) types.Persona {
+	return types.Persona{
+		Id:          persona.Id,
+		Name:        persona.Name,
+		Description: persona.Description,
+		CreatedAt:   persona.CreatedAt.Unix(),
+		UpdatedAt:   persona.UpdatedAt.Unix(),
+	}
+}
+
+// findOwnedPersona 查找当前用户自己的人设
+func findOwnedPersona(ctx context.Context, svcCtx *svc.ServiceContext, id int64) (*model.Persona, error) {
+	userId := ctxdata.GetUidFromCtx(ctx)
+	persona, err := svcCtx.PersonaModel.FindOne(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "personaId: %v,err: %+v", id, err)
+	}
+	if persona.UserId != userId {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REQUEST_ROLE_ERROR), "userId: %d, personaId: %d", userId, id)
+	}
+	return persona, nil
+}
@@ -36,7 +36,7 @@ func (l *LoginLogic) Login(req *types.LoginRequest) (resp *types.LoginResponse,
 	if len(req.Captcha) != 0 {
 		captcha, _ := l.svcCtx.Redis.Get(l.ctx, globalkey.Email(req.Email)).Result()
 		if captcha != req.Captcha {
-			return nil, errors.Wrapf(errorz.NewErrCode(errorz.CAPTCHA_VALIDATE_ERROR), "email: %v,err: %+v", req.Email)
+			return nil, errors.Wrapf(errorz.NewErrCode(errorz.CAPTCHA_VALIDATE_ERROR), "email: %v", req.Email)
 		}
 		return generateToken(l.svcCtx.Config.JwtAuth.AccessExpire, userid.Id)
 	}
@@ -0,0 +1,43 @@
+package user
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/ctxdata"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/model"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type NewPersonaLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewNewPersonaLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NewPersonaLogic {
+	return &NewPersonaLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *NewPersonaLogic) NewPersona(req *types.NewPersonaRequest) (resp *types.NewPersonaResponse, err error) {
+	userId := ctxdata.GetUidFromCtx(l.ctx)
+	persona := &model.Persona{
+		UserId:      userId,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err = l.svcCtx.PersonaModel.Insert(l.ctx, nil, persona); err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "userId: %v,err: %+v", userId, err)
+	}
+	return &types.NewPersonaResponse{
+		Persona: castPersona(persona),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package user
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type UpdatePersonaLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUpdatePersonaLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UpdatePersonaLogic {
+	return &UpdatePersonaLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *UpdatePersonaLogic) UpdatePersona(req *types.UpdatePersonaRequest) (resp *types.UpdatePersonaResponse, err error) {
+	persona, err := findOwnedPersona(l.ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	persona.Name = req.Name
+	persona.Description = req.Description
+	if err = l.svcCtx.PersonaModel.Update(l.ctx, nil, persona); err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.DB_ERROR), "personaId: %v,err: %+v", req.Id, err)
+	}
+	return &types.UpdatePersonaResponse{
+		Persona: castPersona(persona),
+	}, nil
+}
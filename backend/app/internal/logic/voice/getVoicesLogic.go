@@ -0,0 +1,47 @@
+package voice
+
+import (
+	"context"
+	"qiniuyun/backend/common/tts"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetVoicesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetVoicesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetVoicesLogic {
+	return &GetVoicesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetVoicesLogic) GetVoices(req *types.GetVoicesRequest) (resp *types.GetVoicesResponse, err error) {
+	voices := tts.Voices(req.Category, req.Language, req.Gender)
+	res := make([]types.Voice, 0, len(voices))
+	for _, voice := range voices {
+		res = append(res, castVoice(voice))
+	}
+	return &types.GetVoicesResponse{
+		Voices: res,
+	}, nil
+}
+
+func castVoice(voice tts.Voice) types.Voice {
+	return types.Voice{
+		Name:      voice.Name,
+		VoiceType: voice.VoiceType,
+		Category:  voice.Category,
+		Language:  voice.Language,
+		Gender:    voice.Gender,
+		SampleUrl: voice.SampleUrl,
+	}
+}
@@ -0,0 +1,49 @@
+package voice
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"qiniuyun/backend/common/errorz"
+	"qiniuyun/backend/common/tts"
+
+	"qiniuyun/backend/app/internal/svc"
+	"qiniuyun/backend/app/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type PreviewVoiceLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewPreviewVoiceLogic(ctx context.Context, svcCtx *svc.ServiceContext) *PreviewVoiceLogic {
+	return &PreviewVoiceLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *PreviewVoiceLogic) PreviewVoice(req *types.PreviewVoiceRequest) (resp *types.PreviewVoiceResponse, err error) {
+	if _, ok := tts.FindVoice(req.VoiceType); !ok {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.REUQEST_PARAM_ERROR), "voice: %s", req.VoiceType)
+	}
+	params := tts.Params{
+		VoiceType:   req.VoiceType,
+		Encoding:    req.TTSConfig.Encoding,
+		SpeedRatio:  req.TTSConfig.SpeedRatio,
+		PitchRatio:  req.TTSConfig.PitchRatio,
+		VolumeRatio: req.TTSConfig.VolumeRatio,
+		Emotion:     req.TTSConfig.Emotion,
+	}
+	audio, err := l.svcCtx.TTS.Synthesize(l.ctx, params, req.Text)
+	if err != nil {
+		return nil, errors.Wrapf(errorz.NewErrCode(errorz.SERVER_COMMON_ERROR), "tts: %+v", err)
+	}
+	return &types.PreviewVoiceResponse{
+		Audio:    audio,
+		Encoding: params.EncodingOrDefault(),
+	}, nil
+}
@@ -4,11 +4,15 @@ import (
 	"github.com/SpectatorNan/gorm-zero/gormc/config/mysql"
 	"github.com/go-playground/validator/v10"
 	"qiniuyun/backend/common/embedding"
+	"qiniuyun/backend/common/job"
 	"qiniuyun/backend/common/llm"
+	"qiniuyun/backend/common/stt"
+	"qiniuyun/backend/common/tts"
 
 	"github.com/go-redis/redis/v8"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/qdrant/go-client/qdrant"
+	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/rest"
 	"qiniuyun/backend/app/internal/config"
 	"qiniuyun/backend/app/internal/middleware"
@@ -29,8 +33,13 @@ type ServiceContext struct {
 	CharacterTagModel model.CharacterTagModel
 	SessionModel      model.SessionModel
 	MessageModel      model.MessageModel
+	LorebookModel     model.LorebookEntryModel
+	PersonaModel      model.PersonaModel
 	LLM               *llm.Client
 	Embedding         *embedding.Client
+	STT               stt.Provider
+	TTS               *tts.Client
+	Jobs              *job.Dispatcher
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
@@ -39,22 +48,13 @@ func NewServiceContext(c config.Config) *ServiceContext {
 	if err != nil {
 		panic(err)
 	}
-
-	qdrantClient, err := qdrant.NewClient(&qdrant.Config{
-		Host:   c.Qdrant.Host,
-		Port:   c.Qdrant.Port,
-		UseTLS: false,
-	})
-	if err != nil {
-		panic(err)
-	}
-
+	redisClient := redis.NewClient(&redis.Options{Addr: c.Redis.Host, Password: c.Redis.Password})
 	return &ServiceContext{
 		Config:            c,
 		Validate:          validator.New(),
 		Auth:              middleware.AuthMiddleware(),
 		Token:             middleware.TokenMiddleware(),
-		Redis:             redis.NewClient(&redis.Options{Addr: c.Redis.Host, Password: c.Redis.Password}),
+		Redis:             redisClient,
 		UserModel:         model.NewUserModel(db, c.CacheRedis),
 		CharacterModel:    model.NewCharacterModel(db, c.CacheRedis),
 		TagModel:          model.NewTagModel(db, c.CacheRedis),
@@ -62,7 +62,69 @@ func NewServiceContext(c config.Config) *ServiceContext {
 		CharacterTagModel: model.NewCharacterTagModel(db, c.CacheRedis),
 		SessionModel:      model.NewSessionModel(db, c.CacheRedis),
 		MessageModel:      model.NewMessageModel(db, c.CacheRedis),
-		LLM:               llm.New(c.LLM.ApiKey, c.LLM.Model, c.LLM.BaseURL),
-		Embedding:         embedding.New(c.Embedding.BaseURL, qdrantClient),
+		LorebookModel:     model.NewLorebookEntryModel(db, c.CacheRedis),
+		PersonaModel:      model.NewPersonaModel(db, c.CacheRedis),
+		LLM:               llm.New(newLLMProvider(c)),
+		Embedding:         embedding.New(newEmbedder(c), newVectorStore(c)),
+		STT:               newSTT(c),
+		TTS:               tts.New(c.LLM.ApiKey),
+		Jobs:              newJobs(c, redisClient),
+	}
+}
+
+// newJobs 任务状态与队列共用存储：memory 队列使用进程内记录，其余使用 Redis
+func newJobs(c config.Config, redisClient *redis.Client) *job.Dispatcher {
+	if c.Job.Queue == "memory" {
+		return job.NewDispatcher(job.NewMemory(), job.NewMemoryStore(), c.Job.Workers, c.Job.MaxAttempts)
+	}
+	var queue job.Queue = job.NewRedis(redisClient)
+	if c.Job.Queue == "kafka" {
+		if len(c.Kafka.Addr) > 0 {
+			kafkaQueue, err := job.NewKafka(c.Kafka.Addr, c.Kafka.Topic, c.Job.Group, redisClient, c.Job.Workers)
+			if err != nil {
+				panic(err)
+			}
+			queue = kafkaQueue
+		} else {
+			logx.Error("kafka address not configured, fall back to redis job queue")
+		}
+	}
+	return job.NewDispatcher(queue, job.NewRedisStore(redisClient), c.Job.Workers, c.Job.MaxAttempts)
+}
+
+func newEmbedder(c config.Config) embedding.Embedder {
+	if c.Embedding.Provider == "hash" {
+		return embedding.NewHashEmbedder(c.Embedding.Dimension)
+	}
+	return embedding.NewHTTPEmbedder(c.Embedding.BaseURL)
+}
+
+// newVectorStore 只有显式配置为 memory 时才使用内存存储，Qdrant 客户端创建失败时直接退出，避免记忆静默写入内存后丢失
+func newVectorStore(c config.Config) embedding.VectorStore {
+	if c.Embedding.VectorStore == "memory" {
+		return embedding.NewMemoryStore()
+	}
+	qdrantClient, err := qdrant.NewClient(&qdrant.Config{
+		Host:   c.Qdrant.Host,
+		Port:   c.Qdrant.Port,
+		UseTLS: false,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return embedding.NewQdrant(qdrantClient)
+}
+
+func newLLMProvider(c config.Config) llm.Provider {
+	if c.LLM.Provider == "fake" {
+		return llm.NewFake(c.LLM.FakeReplies)
+	}
+	return llm.NewOpenAI(c.LLM.ApiKey, c.LLM.Model, c.LLM.BaseURL)
+}
+
+func newSTT(c config.Config) stt.Provider {
+	if c.STT.Provider == "fake" {
+		return stt.NewFake(c.STT.FakeText)
 	}
+	return stt.NewAli(c.Ali.AccessKey, c.Ali.SecretKey, c.Ali.AppKey)
 }
@@ -1,6 +1,11 @@
 // Code generated by goctl. DO NOT EDIT.
 package types
 
+type ArchiveSessionRequest struct {
+	Id       int64 `path:"id"`
+	Archived bool  `json:"archived"`
+}
+
 type CaptchaRequest struct {
 	Email string `json:"email" validate:"email"`
 }
@@ -10,31 +15,127 @@ type ChangePwdRequest struct {
 }
 
 type Character struct {
-	Id          int64    `json:"id"`
-	Background  string   `json:"background"`
-	Name        string   `json:"name"`
-	Avatar      string   `json:"avatar"`
-	Description string   `json:"description"`
-	OpenLine    string   `json:"open_line"`
-	Tags        []string `json:"tags"`
-	IsPublic    bool     `json:"is_public"`
-	UserId      int64    `json:"user_id"`
-	UserName    string   `json:"user_name"`
-	CreatedAt   int64    `json:"created_at"`
-	UpdatedAt   int64    `json:"updated_at"`
+	Id          int64     `json:"id"`
+	Background  string    `json:"background"`
+	Name        string    `json:"name"`
+	Avatar      string    `json:"avatar"`
+	Description string    `json:"description"`
+	OpenLine    string    `json:"open_line"`
+	Voice       string    `json:"voice"`
+	TTSConfig   TTSConfig `json:"tts_config"`
+	Personality []string  `json:"personality"`
+	Status      int64     `json:"status"` // 生成状态：0 就绪 1 等待生成 2 生成中 3 生成失败
+	Tags        []string  `json:"tags"`
+	IsPublic    bool      `json:"is_public"`
+	UserId      int64     `json:"user_id"`
+	UserName    string    `json:"user_name"`
+	CreatedAt   int64     `json:"created_at"`
+	UpdatedAt   int64     `json:"updated_at"`
+}
+
+type CharacterStats struct {
+	SessionCount int64 `json:"session_count"`
+	MessageCount int64 `json:"message_count"`
+	ChatterCount int64 `json:"chatter_count"` // 与角色对话过的用户数
 }
 
 type ChatRequest struct {
 	SessionId int64 `path:"session_id"`
 }
 
+type DeleteCharacterRequest struct {
+	Id int64 `path:"id"`
+}
+
+type DeleteLorebookEntryRequest struct {
+	CharacterId int64 `path:"id"`
+	EntryId     int64 `path:"entry_id"`
+}
+
+type DeletePersonaRequest struct {
+	Id int64 `path:"id"`
+}
+
+type DeleteSessionRequest struct {
+	Id int64 `path:"id"`
+}
+
+type ExportCharacterRequest struct {
+	Id     int64  `path:"id"`
+	Format string `form:"format,default=json" validate:"oneof=json png"`
+}
+
+type GetBranchesRequest struct {
+	Id int64 `path:"id"`
+}
+
+type GetBranchesResponse struct {
+	Branches []Message `json:"branches"`
+}
+
+type GetCharacterDetailRequest struct {
+	Id int64 `path:"id"`
+}
+
+type GetCharacterDetailResponse struct {
+	Character Character      `json:"character"`
+	Creator   User           `json:"creator"`
+	Stats     CharacterStats `json:"stats"`
+}
+
+type GetCharacterStatusRequest struct {
+	Id int64 `path:"id"`
+}
+
+type GetCharacterStatusResponse struct {
+	Status int64  `json:"status"` // 0 就绪 1 等待生成 2 生成中 3 生成失败
+	Reason string `json:"reason"`
+}
+
+type GetLorebookRequest struct {
+	CharacterId int64 `path:"id"`
+}
+
+type GetLorebookResponse struct {
+	Entries []LorebookEntry `json:"entries"`
+}
+
+type GetMessagesRequest struct {
+	Id     int64 `path:"id"`
+	Before int64 `form:"before,optional"`
+	After  int64 `form:"after,optional"`
+	Limit  int   `form:"limit,optional" validate:"omitempty,max=100"`
+}
+
+type GetMessagesResponse struct {
+	Messages []Message `json:"messages"`
+	HasMore  bool      `json:"has_more"`
+}
+
+type GetPersonasResponse struct {
+	Personas []Persona `json:"personas"`
+}
+
 type GetSessionRequest struct {
-	Cursor   int64 `form:"cursor"`
-	PageSize int64 `form:"pageSize"`
+	Cursor      int64  `form:"cursor,optional"`
+	After       string `form:"after,optional"`
+	PageSize    int64  `form:"pageSize" validate:"required,min=1,max=100"`
+	CharacterId int64  `form:"character_id,optional"`
+	Archived    bool   `form:"archived,optional"`
 }
 
 type GetSessionResponse struct {
-	Sessions []Session `json:"sessions"`
+	Sessions   []Session `json:"sessions"`
+	NextCursor string    `json:"next_cursor"`
+	HasMore    bool      `json:"has_more"`
+}
+
+type GetSummaryRequest struct {
+	Id int64 `path:"id"`
+}
+
+type GetSummaryResponse struct {
+	Summary string `json:"summary"`
 }
 
 type GetUserByNameRequest struct {
@@ -55,6 +156,26 @@ type GetUserResponse struct {
 	User User `json:"user"`
 }
 
+type GetVoicesRequest struct {
+	Category string `form:"category,optional"`
+	Language string `form:"language,optional"`
+	Gender   string `form:"gender,optional"`
+}
+
+type GetVoicesResponse struct {
+	Voices []Voice `json:"voices"`
+}
+
+type ImportCharacterRequest struct {
+	File     string `json:"file" validate:"required"` // base64 编码的 PNG 或 JSON 角色卡
+	Avatar   string `json:"avatar,optional"`
+	IsPublic bool   `json:"is_public,optional"`
+}
+
+type ImportCharacterResponse struct {
+	Character Character `json:"character"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" validate:"email"`
 	Password string `json:"password,optional"`
@@ -67,40 +188,116 @@ type LoginResponse struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
+type LorebookEntry struct {
+	Id          int64    `json:"id"`
+	CharacterId int64    `json:"character_id"`
+	Keywords    []string `json:"keywords"`
+	Content     string   `json:"content"`
+	Priority    int64    `json:"priority"`
+	Position    string   `json:"position"`
+	Enabled     bool     `json:"enabled"`
+	CreatedAt   int64    `json:"created_at"`
+	UpdatedAt   int64    `json:"updated_at"`
+}
+
 type Message struct {
-	Role      string `json:"role"`
-	Content   string `json:"content"`
-	CreatedAt int64  `json:"created_at"`
+	Id         int64  `json:"id"`
+	SessionId  int64  `json:"session_id"`
+	ParentId   int64  `json:"parent_id"`
+	Role       string `json:"role"`
+	Content    string `json:"content"`
+	IsSelected bool   `json:"is_selected"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+type MessageHit struct {
+	Message       Message `json:"message"`
+	SessionTitle  string  `json:"session_title"`
+	CharacterId   int64   `json:"character_id"`
+	CharacterName string  `json:"character_name"`
+	Snippet       string  `json:"snippet"` // 命中位置附近的片段，关键词以 <em> 标记，其余内容已做 HTML 转义
 }
 
 type NewCharacterRequest struct {
-	Background  string  `json:"background"`
-	Name        string  `json:"name"`
-	Avatar      string  `json:"avatar"`
-	Description string  `json:"description"`
-	OpenLine    string  `json:"open_line"`
-	Voice       string  `json:"voice"`
-	Tags        []int64 `json:"tags"`
-	IsPublic    bool    `json:"is_public"`
+	Background  string    `json:"background"`
+	Name        string    `json:"name"`
+	Avatar      string    `json:"avatar"`
+	Description string    `json:"description"`
+	OpenLine    string    `json:"open_line"`
+	Voice       string    `json:"voice"`
+	TTSConfig   TTSConfig `json:"tts_config,optional"`
+	Tags        []int64   `json:"tags"`
+	IsPublic    bool      `json:"is_public"`
 }
 
 type NewCharacterResponse struct {
 	Character Character `json:"character"`
 }
 
+type NewLorebookEntryRequest struct {
+	CharacterId int64    `path:"id"`
+	Keywords    []string `json:"keywords" validate:"required,min=1,max=20,dive,required,max=32"`
+	Content     string   `json:"content" validate:"required,max=2000"`
+	Priority    int64    `json:"priority,optional"`
+	Position    string   `json:"position,default=after" validate:"oneof=before after"`
+	Enabled     bool     `json:"enabled,default=true"`
+}
+
+type NewLorebookEntryResponse struct {
+	Entry LorebookEntry `json:"entry"`
+}
+
+type NewPersonaRequest struct {
+	Name        string `json:"name" validate:"required,max=32"`
+	Description string `json:"description,optional" validate:"max=1000"`
+}
+
+type NewPersonaResponse struct {
+	Persona Persona `json:"persona"`
+}
+
 type NewSessionRequest struct {
 	CharacterId int64 `json:"character_id"`
+	PersonaId   int64 `json:"persona_id,optional"`
 }
 
 type NewSessionResponse struct {
 	SessionId int64 `json:"session_id"`
 }
 
+type Persona struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"created_at"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
+
+type PinSessionRequest struct {
+	Id     int64 `path:"id"`
+	Pinned bool  `json:"pinned"`
+}
+
+type PreviewVoiceRequest struct {
+	VoiceType string    `json:"voice_type" validate:"required"`
+	Text      string    `json:"text" validate:"required,max=100"`
+	TTSConfig TTSConfig `json:"tts_config,optional"`
+}
+
+type PreviewVoiceResponse struct {
+	Audio    []byte `json:"audio"`
+	Encoding string `json:"encoding"`
+}
+
 type RefreshResponse struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
 }
 
+type RegenerateCharacterRequest struct {
+	Id int64 `path:"id"`
+}
+
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"email"`
 	Captcha  string `json:"captcha" validate:"number,len=6"`
@@ -114,20 +311,113 @@ type RegisterResponse struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
+type SearchMessagesRequest struct {
+	Q      string `form:"q" validate:"required,max=100"`
+	Cursor int64  `form:"cursor,optional"`
+	Limit  int    `form:"limit,optional" validate:"omitempty,max=50"`
+}
+
+type SearchMessagesResponse struct {
+	Hits    []MessageHit `json:"hits"`
+	Cursor  int64        `json:"cursor"`
+	HasMore bool         `json:"has_more"`
+}
+
+type SelectMessageRequest struct {
+	Id int64 `path:"id"`
+}
+
 type Session struct {
 	SessionId   int64  `json:"session_id"`
 	UserId      int64  `json:"user_id"`
 	CharacterId int64  `json:"character_id"`
+	PersonaId   int64  `json:"persona_id"`
 	Title       string `json:"title"`
+	IsArchived  bool   `json:"is_archived"`
+	IsPinned    bool   `json:"is_pinned"`
 	CreatedAt   int64  `json:"created_at"`
 	UpdatedAt   int64  `json:"updated_at"`
 }
 
+type SetSessionPersonaRequest struct {
+	Id        int64 `path:"id"`
+	PersonaId int64 `json:"persona_id"`
+}
+
+type TTSConfig struct {
+	Encoding    string  `json:"encoding,optional" validate:"omitempty,oneof=mp3 pcm opus"`
+	SpeedRatio  float64 `json:"speed_ratio,optional" validate:"omitempty,min=0.2,max=3"`
+	PitchRatio  float64 `json:"pitch_ratio,optional" validate:"omitempty,min=0.1,max=3"`
+	VolumeRatio float64 `json:"volume_ratio,optional" validate:"omitempty,min=0.1,max=3"`
+	Emotion     string  `json:"emotion,optional" validate:"max=32"`
+}
+
 type Tag struct {
 	Id   int64  `json:"id"`
 	Name string `json:"name"`
 }
 
+type UpdateCharacterRequest struct {
+	Id          int64     `path:"id"`
+	Background  string    `json:"background"`
+	Name        string    `json:"name"`
+	Avatar      string    `json:"avatar"`
+	Description string    `json:"description"`
+	OpenLine    string    `json:"open_line"`
+	Voice       string    `json:"voice"`
+	TTSConfig   TTSConfig `json:"tts_config,optional"`
+	Tags        []int64   `json:"tags"`
+	IsPublic    bool      `json:"is_public"`
+}
+
+type UpdateCharacterResponse struct {
+	Character Character `json:"character"`
+}
+
+type UpdateCharacterVoiceRequest struct {
+	Id        int64     `path:"id"`
+	Voice     string    `json:"voice"`
+	TTSConfig TTSConfig `json:"tts_config,optional"`
+}
+
+type UpdateCharacterVoiceResponse struct {
+	Character Character `json:"character"`
+}
+
+type UpdateLorebookEntryRequest struct {
+	CharacterId int64    `path:"id"`
+	EntryId     int64    `path:"entry_id"`
+	Keywords    []string `json:"keywords" validate:"required,min=1,max=20,dive,required,max=32"`
+	Content     string   `json:"content" validate:"required,max=2000"`
+	Priority    int64    `json:"priority,optional"`
+	Position    string   `json:"position,default=after" validate:"oneof=before after"`
+	Enabled     bool     `json:"enabled,default=true"`
+}
+
+type UpdateLorebookEntryResponse struct {
+	Entry LorebookEntry `json:"entry"`
+}
+
+type UpdatePersonaRequest struct {
+	Id          int64  `path:"id"`
+	Name        string `json:"name" validate:"required,max=32"`
+	Description string `json:"description,optional" validate:"max=1000"`
+}
+
+type UpdatePersonaResponse struct {
+	Persona Persona `json:"persona"`
+}
+
+type UpdateSessionRequest struct {
+	Id    int64  `path:"id"`
+	Title string `json:"title" validate:"required,max=30"`
+}
+
+type UpdateSummaryRequest struct {
+	Id      int64  `path:"id"`
+	Summary string `json:"summary" validate:"max=5000"`
+}
+
 type UploadTokenRequest struct {
 	Url string `json:"url,optional"`
 	Key string `json:"key,optional"`
@@ -158,3 +448,12 @@ type GetCharacterRequest struct {
 type GetCharacterResponse struct {
 	Characters []Character `json:"characters"`
 }
+
+type Voice struct {
+	Name      string `json:"name"`
+	VoiceType string `json:"voice_type"`
+	Category  string `json:"category"`
+	Language  string `json:"language"`
+	Gender    string `json:"gender"`
+	SampleUrl string `json:"sample_url"`
+}
@@ -120,9 +120,10 @@ func TokenMiddleware() rest.Middleware {
 }
 
 type WSRequest struct {
-	Type    string `json:"type"`
-	Token   string `json:"token"`
-	Content string `json:"content"`
+	Type      string `json:"type"`
+	Token     string `json:"token"`
+	Content   string `json:"content"`
+	MessageId int64  `json:"message_id,omitempty"`
 }
 
 func ValidateWs(req WSRequest) (int64, error) {
@@ -0,0 +1,132 @@
+package card
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	SpecV2        = "chara_card_v2"
+	SpecVersionV2 = "2.0"
+)
+
+const (
+	// PositionBeforeChar 世界书条目插入角色设定之前
+	PositionBeforeChar = "before_char"
+	// PositionAfterChar 世界书条目插入角色设定之后
+	PositionAfterChar = "after_char"
+)
+
+// Card Character Card V2，参考 https://github.com/malfoyslastname/character-card-spec-v2
+type Card struct {
+	Spec        string `json:"spec"`
+	SpecVersion string `json:"spec_version"`
+	Data        Data   `json:"data"`
+}
+
+type Data struct {
+	Name                    string                 `json:"name"`
+	Description             string                 `json:"description"`
+	Personality             string                 `json:"personality"`
+	Scenario                string                 `json:"scenario"`
+	FirstMes                string                 `json:"first_mes"`
+	MesExample              string                 `json:"mes_example"`
+	CreatorNotes            string                 `json:"creator_notes"`
+	SystemPrompt            string                 `json:"system_prompt"`
+	PostHistoryInstructions string                 `json:"post_history_instructions"`
+	AlternateGreetings      []string               `json:"alternate_greetings"`
+	CharacterBook           *Book                  `json:"character_book,omitempty"`
+	Tags                    []string               `json:"tags"`
+	Creator                 string                 `json:"creator"`
+	CharacterVersion        string                 `json:"character_version"`
+	Extensions              map[string]interface{} `json:"extensions"`
+}
+
+// Book 角色自带的世界书
+type Book struct {
+	Name              string                 `json:"name,omitempty"`
+	Description       string                 `json:"description,omitempty"`
+	ScanDepth         int64                  `json:"scan_depth,omitempty"`
+	TokenBudget       int64                  `json:"token_budget,omitempty"`
+	RecursiveScanning bool                   `json:"recursive_scanning,omitempty"`
+	Extensions        map[string]interface{} `json:"extensions"`
+	Entries           []BookEntry            `json:"entries"`
+}
+
+type BookEntry struct {
+	Keys           []string               `json:"keys"`
+	Content        string                 `json:"content"`
+	Extensions     map[string]interface{} `json:"extensions"`
+	Enabled        bool                   `json:"enabled"`
+	InsertionOrder int64                  `json:"insertion_order"`
+	CaseSensitive  bool                   `json:"case_sensitive,omitempty"`
+	Name           string                 `json:"name,omitempty"`
+	Priority       int64                  `json:"priority,omitempty"`
+	Id             int64                  `json:"id,omitempty"`
+	Comment        string                 `json:"comment,omitempty"`
+	Selective      bool                   `json:"selective,omitempty"`
+	SecondaryKeys  []string               `json:"secondary_keys,omitempty"`
+	Constant       bool                   `json:"constant,omitempty"`
+	Position       string                 `json:"position,omitempty"`
+}
+
+var ErrInvalidCard = errors.New("invalid character card")
+
+// New 创建一张 V2 角色卡，补齐规范要求不为 null 的字段
+func New(data Data) *Card {
+	if data.AlternateGreetings == nil {
+		data.AlternateGreetings = []string{}
+	}
+	if data.Tags == nil {
+		data.Tags = []string{}
+	}
+	if data.Extensions == nil {
+		data.Extensions = map[string]interface{}{}
+	}
+	if data.CharacterBook != nil {
+		if data.CharacterBook.Extensions == nil {
+			data.CharacterBook.Extensions = map[string]interface{}{}
+		}
+		for i := range data.CharacterBook.Entries {
+			if data.CharacterBook.Entries[i].Extensions == nil {
+				data.CharacterBook.Entries[i].Extensions = map[string]interface{}{}
+			}
+		}
+	}
+	return &Card{
+		Spec:        SpecV2,
+		SpecVersion: SpecVersionV2,
+		Data:        data,
+	}
+}
+
+// Parse 解析 JSON 或 PNG 格式的角色卡，PNG 角色卡的数据位于 tEXt 块 chara 中。
+// 没有 spec 字段的 V1 角色卡按顶层字段解析
+func Parse(b []byte) (*Card, error) {
+	if bytes.HasPrefix(b, pngSignature) {
+		var err error
+		if b, err = ReadPNG(b); err != nil {
+			return nil, err
+		}
+	}
+	var c Card
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCard
+	}
+	if c.Spec == "" {
+		if err := json.Unmarshal(b, &c.Data); err != nil {
+			return nil, ErrInvalidCard
+		}
+		c.Spec, c.SpecVersion = SpecV2, SpecVersionV2
+	}
+	if c.Spec != SpecV2 || c.Data.Name == "" {
+		return nil, ErrInvalidCard
+	}
+	return &c, nil
+}
+
+// JSON 序列化角色卡
+func (c *Card) JSON() ([]byte, error) {
+	return json.Marshal(c)
+}
@@ -0,0 +1,71 @@
+package card
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestPNGRoundTrip(t *testing.T) {
+	c := New(Data{
+		Name:        "艾拉",
+		Description: "港口的领航员",
+		FirstMes:    "欢迎，{{user}}。",
+		CharacterBook: &Book{Entries: []BookEntry{
+			{Keys: []string{"灯塔"}, Content: "灯塔建于百年前", Enabled: true, Position: PositionBeforeChar},
+		}},
+	})
+	img, err := WritePNG(Placeholder(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 写入角色卡后仍是有效的图片
+	if _, err = png.Decode(bytes.NewReader(img)); err != nil {
+		t.Fatal(err)
+	}
+	// 再次写入会替换已有的角色卡数据
+	c.Data.Name = "新的艾拉"
+	if img, err = WritePNG(img, c); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Data.Name != "新的艾拉" || got.Data.FirstMes != "欢迎，{{user}}。" {
+		t.Fatalf("unexpected card: %+v", got.Data)
+	}
+	if book := got.Data.CharacterBook; book == nil || len(book.Entries) != 1 || book.Entries[0].Content != "灯塔建于百年前" {
+		t.Fatalf("unexpected character book: %+v", got.Data.CharacterBook)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"v2", `{"spec":"chara_card_v2","spec_version":"2.0","data":{"name":"艾拉"}}`, "艾拉", false},
+		{"v1", `{"name":"艾拉","description":"领航员"}`, "艾拉", false},
+		{"unknown spec", `{"spec":"chara_card_v3","data":{"name":"艾拉"}}`, "", true},
+		{"no name", `{"spec":"chara_card_v2","data":{}}`, "", true},
+		{"not json", `hello`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Data.Name != tt.want {
+				t.Fatalf("name = %q, want %q", got.Data.Name, tt.want)
+			}
+		})
+	}
+	// PNG 中没有角色卡数据
+	if _, err := Parse(Placeholder()); err != ErrNoCardData {
+		t.Fatalf("err = %v, want ErrNoCardData", err)
+	}
+}
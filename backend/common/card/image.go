@@ -0,0 +1,40 @@
+package card
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+)
+
+// ToPNG 将 JPEG、GIF 或 PNG 图片转为 PNG，PNG 原样返回
+func ToPNG(b []byte) ([]byte, error) {
+	if bytes.HasPrefix(b, pngSignature) {
+		return b, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Placeholder 角色没有可用头像时导出使用的纯色图片
+func Placeholder() []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 600))
+	bg := color.RGBA{R: 0x2b, G: 0x2d, B: 0x42, A: 0xff}
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 400; x++ {
+			img.SetRGBA(x, y, bg)
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
@@ -0,0 +1,105 @@
+package card
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// chunkKeyword 角色卡数据所在 tEXt 块的关键字，内容为角色卡 JSON 的 base64
+const chunkKeyword = "chara"
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+var ErrNoCardData = errors.New("png has no character card data")
+
+type chunk struct {
+	typ  string
+	data []byte
+}
+
+// ReadPNG 从 PNG 中读取角色卡 JSON
+func ReadPNG(b []byte) ([]byte, error) {
+	chunks, err := readChunks(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chunks {
+		if c.typ != "tEXt" {
+			continue
+		}
+		keyword, text, ok := bytes.Cut(c.data, []byte{0})
+		if !ok || string(keyword) != chunkKeyword {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(string(text))
+		if err != nil {
+			return nil, ErrInvalidCard
+		}
+		return data, nil
+	}
+	return nil, ErrNoCardData
+}
+
+// WritePNG 将角色卡写入 PNG 的 tEXt 块，替换图片中已有的角色卡数据
+func WritePNG(img []byte, c *Card) ([]byte, error) {
+	chunks, err := readChunks(img)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.JSON()
+	if err != nil {
+		return nil, err
+	}
+	text := append([]byte(chunkKeyword+"\x00"), base64.StdEncoding.EncodeToString(data)...)
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	for _, ch := range chunks {
+		if ch.typ == "tEXt" && bytes.HasPrefix(ch.data, []byte(chunkKeyword+"\x00")) {
+			continue
+		}
+		if ch.typ == "IEND" {
+			writeChunk(&buf, chunk{typ: "tEXt", data: text})
+		}
+		writeChunk(&buf, ch)
+	}
+	return buf.Bytes(), nil
+}
+
+func readChunks(b []byte) ([]chunk, error) {
+	if !bytes.HasPrefix(b, pngSignature) {
+		return nil, ErrInvalidCard
+	}
+	b = b[len(pngSignature):]
+	var chunks []chunk
+	for len(b) >= 12 {
+		length := binary.BigEndian.Uint32(b[:4])
+		if uint64(length)+12 > uint64(len(b)) {
+			return nil, ErrInvalidCard
+		}
+		c := chunk{typ: string(b[4:8]), data: b[8 : 8+length]}
+		chunks = append(chunks, c)
+		b = b[12+length:]
+		if c.typ == "IEND" {
+			return chunks, nil
+		}
+	}
+	return nil, ErrInvalidCard
+}
+
+func writeChunk(buf *bytes.Buffer, c chunk) {
+	var head [8]byte
+	binary.BigEndian.PutUint32(head[:4], uint32(len(c.data)))
+	copy(head[4:], c.typ)
+	buf.Write(head[:])
+	buf.Write(c.data)
+	crc := crc32.NewIEEE()
+	crc.Write(head[4:])
+	crc.Write(c.data)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	buf.Write(sum[:])
+}
@@ -1,19 +1,14 @@
 package embedding
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"github.com/google/uuid"
-	"github.com/qdrant/go-client/qdrant"
-	"github.com/zeromicro/go-zero/core/logx"
-	"io/ioutil"
-	"net/http"
 )
 
 const (
-	fieldText = "text"
+	// SourceCharacter 角色自身的记忆，由角色背景生成
+	SourceCharacter = "character"
+	// SourceConversation 从用户与角色的对话中提取的记忆
+	SourceConversation = "conversation"
 )
 
 var (
@@ -21,90 +16,88 @@ var (
 	limit     = uint64(5)
 )
 
-// Client 用于调用 Python Embedding 服务和 Qdrant 检索
+// Payload 与向量一同存储的记忆信息
+type Payload struct {
+	UserId    int64 // 记忆所属用户，0 表示角色自身的记忆，所有用户共享
+	SessionId int64
+	Source    string
+	CreatedAt int64
+}
+
+// Memory 检索到的一条记忆
+type Memory struct {
+	Text string
+	Payload
+}
+
+// Embedder 文本向量化
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	// EmbedBatch 一次请求向量化多条文本，结果与 texts 一一对应
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// VectorStore 向量存储与检索，每个 collection 相互独立
+type VectorStore interface {
+	// Insert 写入文本及其向量，同一批写入共用 payload，collection 不存在时自动创建
+	Insert(ctx context.Context, collection string, texts []string, vectors [][]float32, payload Payload) error
+	// Search 按余弦相似度检索共享记忆及 userId 自己的记忆，返回相似度不低于 threshold 的前 limit 条
+	Search(ctx context.Context, collection string, vector []float32, userId int64, limit uint64, threshold float32) ([]Memory, error)
+	// DeleteShared 删除角色自身的共享记忆，用户的记忆保留，collection 不存在时不报错
+	DeleteShared(ctx context.Context, collection string) error
+	// DeleteSession 删除从会话中提取的记忆，collection 不存在时不报错
+	DeleteSession(ctx context.Context, collection string, sessionId int64) error
+	// Drop 删除整个 collection，collection 不存在时不报错
+	Drop(ctx context.Context, collection string) error
+}
+
+// Client 组合向量化与向量存储，供记忆写入与检索使用
 type Client struct {
-	baseURL string
-	qdrant  *qdrant.Client
+	embedder Embedder
+	store    VectorStore
 }
 
 // New 创建客户端
-func New(baseURL string, qdrantClient *qdrant.Client) *Client {
+func New(embedder Embedder, store VectorStore) *Client {
 	return &Client{
-		baseURL: baseURL,
-		qdrant:  qdrantClient,
+		embedder: embedder,
+		store:    store,
 	}
 }
 
 // GetEmbedding 获取文本向量
 func (c *Client) GetEmbedding(text string) ([]float32, error) {
-	payload := map[string]string{"text": text}
-	body, _ := json.Marshal(payload)
+	return c.embedder.Embed(context.Background(), text)
+}
 
-	resp, err := http.Post(fmt.Sprintf("%s/embed", c.baseURL), "application/json", bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
+// GetEmbeddings 批量获取文本向量
+func (c *Client) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
 	}
-	defer resp.Body.Close()
+	return c.embedder.EmbedBatch(ctx, texts)
+}
 
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+func (c *Client) InsertVectors(ctx context.Context, collection string, texts []string, vectors [][]float32, payload Payload) error {
+	return c.store.Insert(ctx, collection, texts, vectors, payload)
+}
 
-	var result struct {
-		Vector []float32 `json:"vector"`
-	}
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, err
-	}
-	fmt.Println("vector len:", len(result.Vector))
-	return result.Vector, nil
+// Search 检索 userId 可见的记忆：角色自身的共享记忆与该用户自己的记忆，其他用户的记忆不会返回
+func (c *Client) Search(collection string, vector []float32, userId int64) ([]Memory, error) {
+	return c.store.Search(context.Background(), collection, vector, userId, limit, threshold)
 }
 
-func (c *Client) InsertVectors(ctx context.Context, collection string, texts []string, vectors [][]float32) error {
-	if err := c.qdrant.CreateCollection(ctx, &qdrant.CreateCollection{
-		CollectionName: collection,
-		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
-			Size:     384,
-			Distance: qdrant.Distance_Cosine,
-		}),
-	}); err != nil {
-		logx.Error(err)
-	}
+// DeleteShared 删除角色自身的共享记忆，用于角色背景修改后重建记忆
+func (c *Client) DeleteShared(ctx context.Context, collection string) error {
+	return c.store.DeleteShared(ctx, collection)
+}
 
-	var points []*qdrant.PointStruct
-	for i, vector := range vectors {
-		point := &qdrant.PointStruct{
-			Id:      qdrant.NewIDUUID(uuid.New().String()),
-			Vectors: qdrant.NewVectors(vector...),
-			Payload: qdrant.NewValueMap(map[string]any{fieldText: texts[i]}),
-		}
-		points = append(points, point)
-	}
-	_, err := c.qdrant.Upsert(ctx, &qdrant.UpsertPoints{
-		CollectionName: collection,
-		Points:         points,
-	})
-	return err
+// DeleteSession 删除从会话中提取的记忆，用于删除会话
+func (c *Client) DeleteSession(ctx context.Context, collection string, sessionId int64) error {
+	return c.store.DeleteSession(ctx, collection, sessionId)
 }
 
-func (c *Client) Search(collection string, vector []float32) ([]string, error) {
-	points, err := c.qdrant.Query(context.Background(), &qdrant.QueryPoints{
-		CollectionName: collection,
-		Query:          qdrant.NewQuery(vector...),
-		WithPayload:    qdrant.NewWithPayloadInclude(fieldText),
-		ScoreThreshold: &threshold,
-		Limit:          &limit,
-	})
-	if err != nil {
-		logx.Error(err)
-		return nil, err
-	}
-	var texts []string
-	for _, point := range points {
-		fmt.Println(point.Score)
-		texts = append(texts, point.Payload[fieldText].GetStringValue())
-	}
-	fmt.Println(texts)
-	return texts, nil
+// Drop 删除角色的全部记忆
+func (c *Client) Drop(ctx context.Context, collection string) error {
+	return c.store.Drop(ctx, collection)
 }
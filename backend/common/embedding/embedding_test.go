@@ -0,0 +1,128 @@
+package embedding
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func newTestClient() *Client {
+	return New(NewHashEmbedder(256), NewMemoryStore())
+}
+
+func insert(t *testing.T, c *Client, collection string, payload Payload, texts ...string) {
+	t.Helper()
+	var vectors [][]float32
+	for _, text := range texts {
+		vector, err := c.GetEmbedding(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vectors = append(vectors, vector)
+	}
+	if err := c.InsertVectors(context.Background(), collection, texts, vectors, payload); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func search(t *testing.T, c *Client, collection, query string, userId int64) []string {
+	t.Helper()
+	vector, err := c.GetEmbedding(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memories, err := c.Search(collection, vector, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, m := range memories {
+		texts = append(texts, m.Text)
+	}
+	return texts
+}
+
+func TestHashEmbedderSimilarity(t *testing.T) {
+	e := NewHashEmbedder(256)
+	ctx := context.Background()
+	query, _ := e.Embed(ctx, "她喜欢下雨天")
+	near, _ := e.Embed(ctx, "她最喜欢下雨天")
+	far, _ := e.Embed(ctx, "The quick brown fox")
+	if s := cosine(query, near); s < threshold {
+		t.Fatalf("similar texts score %f, want >= %f", s, threshold)
+	}
+	if cosine(query, far) >= cosine(query, near) {
+		t.Fatal("unrelated text scores higher than a similar one")
+	}
+	again, _ := e.Embed(ctx, "她喜欢下雨天")
+	if s := cosine(query, again); s < 0.999 {
+		t.Fatalf("embedding is not deterministic, self similarity %f", s)
+	}
+}
+
+func TestMemoryStoreSearch(t *testing.T) {
+	c := newTestClient()
+	insert(t, c, "character_1", Payload{Source: SourceCharacter}, "艾拉喜欢下雨天", "艾拉住在港口", "The quick brown fox")
+
+	// 按相似度排序，低于阈值的文本不返回
+	texts := search(t, c, "character_1", "艾拉喜欢下雨天吗", 1)
+	if len(texts) == 0 || texts[0] != "艾拉喜欢下雨天" || slices.Contains(texts, "The quick brown fox") {
+		t.Fatalf("unexpected search result: %q", texts)
+	}
+	if texts = search(t, c, "character_2", "艾拉喜欢下雨天", 1); len(texts) != 0 {
+		t.Fatalf("collections are not isolated: %q", texts)
+	}
+}
+
+func TestMemoryStoreSearchLimit(t *testing.T) {
+	c := newTestClient()
+	var texts []string
+	for i := 0; i < int(limit)+3; i++ {
+		texts = append(texts, "艾拉喜欢下雨天")
+	}
+	insert(t, c, "character_1", Payload{Source: SourceCharacter}, texts...)
+	if got := search(t, c, "character_1", "艾拉喜欢下雨天", 1); len(got) != int(limit) {
+		t.Fatalf("search returned %d texts, want %d", len(got), limit)
+	}
+}
+
+func TestMemoryStoreUserIsolation(t *testing.T) {
+	c := newTestClient()
+	insert(t, c, "character_1", Payload{Source: SourceCharacter}, "艾拉喜欢下雨天")
+	insert(t, c, "character_1", Payload{UserId: 1, SessionId: 11, Source: SourceConversation}, "旅行者喜欢下雨天")
+	insert(t, c, "character_1", Payload{UserId: 2, SessionId: 21, Source: SourceConversation}, "路人喜欢下雨天")
+
+	// 共享记忆与自己的记忆可见，其他用户的记忆不可见
+	texts := search(t, c, "character_1", "喜欢下雨天", 1)
+	if len(texts) != 2 || slices.Contains(texts, "路人喜欢下雨天") {
+		t.Fatalf("user 1 sees %q, want shared and own memories", texts)
+	}
+	if texts = search(t, c, "character_1", "喜欢下雨天", 3); len(texts) != 1 || texts[0] != "艾拉喜欢下雨天" {
+		t.Fatalf("user 3 sees %q, want only shared memories", texts)
+	}
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	c := newTestClient()
+	ctx := context.Background()
+	insert(t, c, "character_1", Payload{Source: SourceCharacter}, "艾拉喜欢下雨天")
+	insert(t, c, "character_1", Payload{UserId: 1, SessionId: 11, Source: SourceConversation}, "旅行者喜欢下雨天")
+
+	// 只删除共享记忆，用户的记忆保留
+	if err := c.DeleteShared(ctx, "character_1"); err != nil {
+		t.Fatal(err)
+	}
+	if texts := search(t, c, "character_1", "喜欢下雨天", 1); len(texts) != 1 || texts[0] != "旅行者喜欢下雨天" {
+		t.Fatalf("after DeleteShared user 1 sees %q", texts)
+	}
+	if err := c.Drop(ctx, "character_1"); err != nil {
+		t.Fatal(err)
+	}
+	if texts := search(t, c, "character_1", "喜欢下雨天", 1); len(texts) != 0 {
+		t.Fatalf("after Drop user 1 sees %q", texts)
+	}
+	// collection 不存在时不报错
+	if err := c.DeleteShared(ctx, "character_2"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// HashEmbedder 不依赖模型的确定性向量化：把文本切分为词（英文单词、数字）以及汉字的单字与相邻两字，
+// 用特征哈希映射到固定维度并归一化。语义能力有限，字面重合越多相似度越高，用于测试与单机开发
+type HashEmbedder struct {
+	dim int
+}
+
+func NewHashEmbedder(dim int) *HashEmbedder {
+	return &HashEmbedder{dim: dim}
+}
+
+func (e *HashEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector, _ := e.Embed(ctx, text)
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+func (e *HashEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector := make([]float32, e.dim)
+	for _, feature := range features(text) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		// 最高位决定符号，减小哈希冲突带来的偏差
+		if sum>>63 == 1 {
+			vector[sum%uint64(e.dim)] -= 1
+		} else {
+			vector[sum%uint64(e.dim)] += 1
+		}
+	}
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] = float32(float64(vector[i]) / norm)
+		}
+	}
+	return vector, nil
+}
+
+func features(text string) []string {
+	var res []string
+	var word strings.Builder
+	var prev rune
+	flush := func() {
+		if word.Len() > 0 {
+			res = append(res, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			res = append(res, string(r))
+			if prev != 0 {
+				res = append(res, string([]rune{prev, r}))
+			}
+			prev = r
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+		prev = 0
+	}
+	flush()
+	return res
+}
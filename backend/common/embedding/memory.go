@@ -0,0 +1,115 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// MemoryStore 纯内存的向量存储，暴力计算余弦相似度，用于测试与单机开发，进程退出后数据丢失
+type MemoryStore struct {
+	mu          sync.RWMutex
+	collections map[string][]memoryPoint
+}
+
+type memoryPoint struct {
+	Memory
+	vector []float32
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{collections: make(map[string][]memoryPoint)}
+}
+
+func (m *MemoryStore) Insert(ctx context.Context, collection string, texts []string, vectors [][]float32, payload Payload) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, vector := range vectors {
+		m.collections[collection] = append(m.collections[collection], memoryPoint{
+			Memory: Memory{Text: texts[i], Payload: payload},
+			vector: vector,
+		})
+	}
+	return nil
+}
+
+func (m *MemoryStore) Search(ctx context.Context, collection string, vector []float32, userId int64, limit uint64, threshold float32) ([]Memory, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	type scored struct {
+		Memory
+		score float32
+	}
+	var hits []scored
+	for _, point := range m.collections[collection] {
+		if point.UserId != 0 && point.UserId != userId {
+			continue
+		}
+		score := cosine(vector, point.vector)
+		if score >= threshold {
+			hits = append(hits, scored{Memory: point.Memory, score: score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].score > hits[j].score
+	})
+	if uint64(len(hits)) > limit {
+		hits = hits[:limit]
+	}
+	var memories []Memory
+	for _, hit := range hits {
+		memories = append(memories, hit.Memory)
+	}
+	return memories, nil
+}
+
+func (m *MemoryStore) DeleteShared(ctx context.Context, collection string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	points := m.collections[collection][:0]
+	for _, point := range m.collections[collection] {
+		if point.UserId != 0 {
+			points = append(points, point)
+		}
+	}
+	m.collections[collection] = points
+	return nil
+}
+
+func (m *MemoryStore) DeleteSession(ctx context.Context, collection string, sessionId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	points := m.collections[collection][:0]
+	for _, point := range m.collections[collection] {
+		if point.SessionId != sessionId {
+			points = append(points, point)
+		}
+	}
+	m.collections[collection] = points
+	return nil
+}
+
+func (m *MemoryStore) Drop(ctx context.Context, collection string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.collections, collection)
+	return nil
+}
+
+// cosine 余弦相似度，维度不一致或存在零向量时为 0
+func cosine(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
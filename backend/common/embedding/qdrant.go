@@ -0,0 +1,150 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/qdrant/go-client/qdrant"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	fieldText      = "text"
+	fieldUserId    = "user_id"
+	fieldSessionId = "session_id"
+	fieldSource    = "source"
+	fieldCreatedAt = "created_at"
+)
+
+// Qdrant 基于 Qdrant 的向量存储
+type Qdrant struct {
+	client *qdrant.Client
+}
+
+func NewQdrant(client *qdrant.Client) *Qdrant {
+	return &Qdrant{client: client}
+}
+
+func (q *Qdrant) Insert(ctx context.Context, collection string, texts []string, vectors [][]float32, payload Payload) error {
+	if len(vectors) == 0 {
+		return nil
+	}
+	if err := q.client.CreateCollection(ctx, &qdrant.CreateCollection{
+		CollectionName: collection,
+		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+			Size:     uint64(len(vectors[0])),
+			Distance: qdrant.Distance_Cosine,
+		}),
+	}); err != nil {
+		logx.Error(err)
+	} else if _, err := q.client.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+		CollectionName: collection,
+		FieldName:      fieldUserId,
+		FieldType:      qdrant.FieldType_FieldTypeInteger.Enum(),
+	}); err != nil {
+		logx.Error(err)
+	}
+
+	var points []*qdrant.PointStruct
+	for i, vector := range vectors {
+		point := &qdrant.PointStruct{
+			Id:      qdrant.NewIDUUID(pointId(collection, payload, texts[i])),
+			Vectors: qdrant.NewVectors(vector...),
+			Payload: qdrant.NewValueMap(map[string]any{
+				fieldText:      texts[i],
+				fieldUserId:    payload.UserId,
+				fieldSessionId: payload.SessionId,
+				fieldSource:    payload.Source,
+				fieldCreatedAt: payload.CreatedAt,
+			}),
+		}
+		points = append(points, point)
+	}
+	_, err := q.client.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: collection,
+		Points:         points,
+	})
+	return err
+}
+
+// pointId 由集合、所属用户、会话与文本生成固定的 ID，任务重试时重复写入同一条记忆会覆盖而不是新增
+func pointId(collection string, payload Payload, text string) string {
+	name := fmt.Sprintf("%s/%d/%d/%s", collection, payload.UserId, payload.SessionId, text)
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)).String()
+}
+
+func (q *Qdrant) Search(ctx context.Context, collection string, vector []float32, userId int64, limit uint64, threshold float32) ([]Memory, error) {
+	points, err := q.client.Query(ctx, &qdrant.QueryPoints{
+		CollectionName: collection,
+		Query:          qdrant.NewQuery(vector...),
+		// 早期写入的记忆没有 user_id，视为角色的共享记忆
+		Filter: &qdrant.Filter{
+			Should: []*qdrant.Condition{
+				qdrant.NewIsEmpty(fieldUserId),
+				qdrant.NewMatchInt(fieldUserId, 0),
+				qdrant.NewMatchInt(fieldUserId, userId),
+			},
+		},
+		WithPayload:    qdrant.NewWithPayload(true),
+		ScoreThreshold: &threshold,
+		Limit:          &limit,
+	})
+	if err != nil {
+		logx.Error(err)
+		return nil, err
+	}
+	var memories []Memory
+	for _, point := range points {
+		memories = append(memories, Memory{
+			Text: point.Payload[fieldText].GetStringValue(),
+			Payload: Payload{
+				UserId:    point.Payload[fieldUserId].GetIntegerValue(),
+				SessionId: point.Payload[fieldSessionId].GetIntegerValue(),
+				Source:    point.Payload[fieldSource].GetStringValue(),
+				CreatedAt: point.Payload[fieldCreatedAt].GetIntegerValue(),
+			},
+		})
+	}
+	return memories, nil
+}
+
+func (q *Qdrant) DeleteShared(ctx context.Context, collection string) error {
+	exists, err := q.client.CollectionExists(ctx, collection)
+	if err != nil || !exists {
+		return err
+	}
+	_, err = q.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: collection,
+		Points: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Should: []*qdrant.Condition{
+				qdrant.NewIsEmpty(fieldUserId),
+				qdrant.NewMatchInt(fieldUserId, 0),
+			},
+		}),
+	})
+	return err
+}
+
+func (q *Qdrant) DeleteSession(ctx context.Context, collection string, sessionId int64) error {
+	exists, err := q.client.CollectionExists(ctx, collection)
+	if err != nil || !exists {
+		return err
+	}
+	_, err = q.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: collection,
+		Points: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must: []*qdrant.Condition{
+				qdrant.NewMatchInt(fieldSessionId, sessionId),
+			},
+		}),
+	})
+	return err
+}
+
+func (q *Qdrant) Drop(ctx context.Context, collection string) error {
+	exists, err := q.client.CollectionExists(ctx, collection)
+	if err != nil || !exists {
+		return err
+	}
+	return q.client.DeleteCollection(ctx, collection)
+}
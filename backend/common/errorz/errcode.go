@@ -21,3 +21,10 @@ const (
 	EMAIL_SEND_ERROR
 	PASSWORD_VALIDATE_ERROR
 )
+
+// 角色模块
+const (
+	CHARACTER_GENERATING_ERROR uint32 = 300001 + iota
+	CHARACTER_NOT_READY_ERROR
+	CHARACTER_NOT_FOUND_ERROR
+)
@@ -15,6 +15,10 @@ func init() {
 	message[EMAIL_SEND_ERROR] = "邮件发送失败,请稍后再试"
 	message[CAPTCHA_VALIDATE_ERROR] = "验证码错误"
 	message[PASSWORD_VALIDATE_ERROR] = "密码错误"
+	//角色模块
+	message[CHARACTER_GENERATING_ERROR] = "角色正在生成中,请稍后再试"
+	message[CHARACTER_NOT_READY_ERROR] = "角色生成失败,请重新生成"
+	message[CHARACTER_NOT_FOUND_ERROR] = "角色不存在"
 }
 
 func MapErrMsg(errcode uint32) string {
@@ -0,0 +1,271 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// jobTimeout 单次执行的超时时间
+	jobTimeout = 5 * time.Minute
+	// keyTTL 任务唯一键的过期时间，防止进程崩溃后唯一键一直占用
+	keyTTL = time.Hour
+	// doneTTL 已完成任务的记录保留时间，期间重复投递的任务不会再次执行
+	doneTTL = 24 * time.Hour
+	// maxBackoff 重试间隔上限
+	maxBackoff = 2 * time.Minute
+)
+
+// Job 一个后台任务
+type Job struct {
+	Id          string          `json:"id"`            // 每次提交唯一，用于识别重复投递
+	Key         string          `json:"key,omitempty"` // 唯一键，同一唯一键的任务同时只有一个在排队或执行，参数应当相同
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempt     int             `json:"attempt"` // 已失败的次数
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       int64           `json:"run_at"` // 最早执行时间，unix 毫秒
+	Error       string          `json:"error,omitempty"`
+}
+
+// Bind 解析任务参数
+func (j *Job) Bind(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Final 本次执行是否为最后一次尝试，失败后任务进入死信
+func (j *Job) Final() bool {
+	return j.Attempt+1 >= j.MaxAttempts
+}
+
+// Handler 执行任务。任务可能被重复投递或在失败后重试，Handler 需要保证重复执行的结果一致
+type Handler func(ctx context.Context, job *Job) error
+
+// Queue 任务队列
+type Queue interface {
+	// Enqueue 写入任务，RunAt 之前不会被取出
+	Enqueue(ctx context.Context, job *Job) error
+	// Consume 依次取出到期的任务交给 handle，handle 返回后确认任务，ctx 取消时返回。可被多个 worker 同时调用
+	Consume(ctx context.Context, handle func(ctx context.Context, job *Job)) error
+	// DeadLetter 保存重试耗尽的任务
+	DeadLetter(ctx context.Context, job *Job) error
+}
+
+// Store 记录任务状态，实现唯一键与重复投递的幂等
+type Store interface {
+	// Claim 占用唯一键，已被占用时将其标记为需要再执行一次并返回 false
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Release 释放唯一键。唯一键被标记为需要再执行一次时清除标记、继续占用并返回 true，由调用方再提交一次任务。
+	// 与 Claim 的检查和修改都需是原子的，否则释放前的提交可能丢失
+	Release(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	IsDone(ctx context.Context, id string) (bool, error)
+	MarkDone(ctx context.Context, id string, ttl time.Duration) error
+}
+
+// Dispatcher 提交任务并按类型分发给 Handler，失败的任务按指数退避重试，重试耗尽后进入死信
+type Dispatcher struct {
+	queue       Queue
+	store       Store
+	workers     int
+	maxAttempts int
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher(queue Queue, store Store, workers, maxAttempts int) *Dispatcher {
+	return &Dispatcher{
+		queue:       queue,
+		store:       store,
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		handlers:    make(map[string]Handler),
+	}
+}
+
+// Handle 注册任务类型的 Handler，需在 Start 之前调用
+func (d *Dispatcher) Handle(typ string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[typ] = handler
+}
+
+// Enqueue 提交任务。key 不为空时，同一 key 已有任务在排队或执行则不再重复入队，
+// 而是在该任务结束后再执行一次，执行期间的多次提交合并为一次
+func (d *Dispatcher) Enqueue(ctx context.Context, typ string, key string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		ok, err := d.store.Claim(ctx, key, keyTTL)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+	job := &Job{
+		Id:          uuid.New().String(),
+		Key:         key,
+		Type:        typ,
+		Payload:     data,
+		MaxAttempts: d.maxAttempts,
+		RunAt:       time.Now().UnixMilli(),
+	}
+	if err = d.queue.Enqueue(ctx, job); err != nil {
+		if key != "" {
+			if _, e := d.store.Release(ctx, key, keyTTL); e != nil {
+				logx.Error(e)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Start 启动 worker 消费任务
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			if err := d.queue.Consume(ctx, d.process); err != nil {
+				logx.Errorf("job worker stopped: %+v", err)
+			}
+		}()
+	}
+}
+
+// Stop 停止消费并等待执行中的任务结束
+func (d *Dispatcher) Stop() {
+	if d.cancel == nil {
+		return
+	}
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) process(ctx context.Context, job *Job) {
+	// 停止时让执行中的任务完成，不随 worker 一起取消
+	ctx = context.WithoutCancel(ctx)
+	if done, err := d.store.IsDone(ctx, job.Id); err != nil {
+		logx.Error(err)
+	} else if done {
+		return
+	}
+	d.mu.RLock()
+	handler, ok := d.handlers[job.Type]
+	d.mu.RUnlock()
+
+	var err error
+	if ok {
+		err = d.run(ctx, handler, job)
+	} else {
+		err = fmt.Errorf("no handler for job type %s", job.Type)
+		job.Attempt = job.MaxAttempts
+	}
+	if err == nil {
+		if err = d.store.MarkDone(ctx, job.Id, doneTTL); err != nil {
+			logx.Error(err)
+		}
+		d.release(ctx, job)
+		return
+	}
+
+	job.Error = err.Error()
+	if job.Final() {
+		logx.Errorf("job %s(%s) dead after %d attempts: %s", job.Type, job.Id, job.Attempt+1, job.Error)
+		if err = d.queue.DeadLetter(ctx, job); err != nil {
+			logx.Error(err)
+		}
+		d.release(ctx, job)
+		return
+	}
+	job.Attempt++
+	job.RunAt = time.Now().Add(backoff(job.Attempt)).UnixMilli()
+	logx.Infof("job %s(%s) failed, retry %d: %s", job.Type, job.Id, job.Attempt, job.Error)
+	if err = d.queue.Enqueue(ctx, job); err != nil {
+		logx.Errorf("requeue job %s(%s): %+v", job.Type, job.Id, err)
+		d.release(ctx, job)
+	}
+}
+
+// run 执行任务，Handler panic 视为失败
+func (d *Dispatcher) run(ctx context.Context, handler Handler, job *Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// release 任务结束后释放唯一键，执行期间同一唯一键有新的提交时继续占用并重新提交一次
+func (d *Dispatcher) release(ctx context.Context, job *Job) {
+	if job.Key == "" {
+		return
+	}
+	rerun, err := d.store.Release(ctx, job.Key, keyTTL)
+	if err != nil {
+		logx.Error(err)
+		return
+	}
+	if !rerun {
+		return
+	}
+	next := &Job{
+		Id:          uuid.New().String(),
+		Key:         job.Key,
+		Type:        job.Type,
+		Payload:     job.Payload,
+		MaxAttempts: d.maxAttempts,
+		RunAt:       time.Now().UnixMilli(),
+	}
+	if err = d.queue.Enqueue(ctx, next); err != nil {
+		logx.Errorf("rerun job %s(%s): %+v", job.Type, job.Key, err)
+		if _, err = d.store.Release(ctx, job.Key, keyTTL); err != nil {
+			logx.Error(err)
+		}
+	}
+}
+
+// backoff 第 attempt 次重试前的等待时间：1s、2s、4s……，不超过 maxBackoff，附加 20% 以内的随机抖动
+func backoff(attempt int) time.Duration {
+	wait := maxBackoff
+	if attempt < 8 {
+		wait = min(time.Second<<(attempt-1), maxBackoff)
+	}
+	return wait + time.Duration(rand.Int63n(int64(wait)/5+1))
+}
+
+// sleepUntil 等待到 runAt，ctx 取消时返回 false
+func sleepUntil(ctx context.Context, runAt int64) bool {
+	wait := time.Until(time.UnixMilli(runAt))
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
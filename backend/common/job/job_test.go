@@ -0,0 +1,230 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestDispatcher(t *testing.T, workers, maxAttempts int) (*Dispatcher, *Memory, *MemoryStore) {
+	queue, store := NewMemory(), NewMemoryStore()
+	d := NewDispatcher(queue, store, workers, maxAttempts)
+	t.Cleanup(d.Stop)
+	return d, queue, store
+}
+
+// waitFor 等待 cond 成立，超时则测试失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// claimed 唯一键是否被占用，不像 Claim 那样标记再执行
+func claimed(store *MemoryStore, key string) bool {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	_, ok := store.keys[key]
+	return ok
+}
+
+func TestDispatcherRetryThenDeadLetter(t *testing.T) {
+	d, queue, store := newTestDispatcher(t, 1, 2)
+	var attempts atomic.Int32
+	d.Handle("fail", func(ctx context.Context, job *Job) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	})
+	d.Start()
+
+	if err := d.Enqueue(context.Background(), "fail", "fail:1", nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "dead letter", func() bool { return len(queue.Dead()) == 1 })
+
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("attempts = %d, want 2", n)
+	}
+	dead := queue.Dead()[0]
+	if dead.Attempt != 1 || dead.Error != "boom" {
+		t.Fatalf("dead job attempt = %d, error = %q", dead.Attempt, dead.Error)
+	}
+	// 进入死信后释放唯一键
+	if ok, _ := store.Claim(context.Background(), "fail:1", time.Minute); !ok {
+		t.Fatal("unique key not released after dead letter")
+	}
+}
+
+func TestDispatcherSkipsRedeliveredJob(t *testing.T) {
+	d, queue, _ := newTestDispatcher(t, 1, 3)
+	var runs atomic.Int32
+	ids := make(chan string, 1)
+	d.Handle("once", func(ctx context.Context, job *Job) error {
+		runs.Add(1)
+		ids <- job.Id
+		return nil
+	})
+	var checked atomic.Bool
+	d.Handle("check", func(ctx context.Context, job *Job) error {
+		checked.Store(true)
+		return nil
+	})
+	d.Start()
+
+	if err := d.Enqueue(context.Background(), "once", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	id := <-ids
+
+	// 模拟 worker 确认前崩溃导致的重复投递：同一 id 的任务再次入队
+	now := time.Now().UnixMilli()
+	if err := queue.Enqueue(context.Background(), &Job{Id: id, Type: "once", MaxAttempts: 3, RunAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	// 单个 worker 按执行时间顺序取出，check 执行时重复投递的任务已处理
+	if err := queue.Enqueue(context.Background(), &Job{Id: "check", Type: "check", MaxAttempts: 3, RunAt: now + 1}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "check job", checked.Load)
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("runs = %d, want 1", n)
+	}
+}
+
+func TestDispatcherUniqueKey(t *testing.T) {
+	d, _, store := newTestDispatcher(t, 2, 3)
+	var runs atomic.Int32
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	d.Handle("slow", func(ctx context.Context, job *Job) error {
+		runs.Add(1)
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	d.Start()
+
+	ctx := context.Background()
+	if err := d.Enqueue(ctx, "slow", "slow:1", nil); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	// 同一唯一键的任务执行中，再次提交不会入队，空闲的 worker 不会取到它
+	for i := 0; i < 2; i++ {
+		if err := d.Enqueue(ctx, "slow", "slow:1", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-started:
+		t.Fatal("job with a claimed key ran concurrently")
+	case <-time.After(200 * time.Millisecond):
+	}
+	// 执行期间的提交在任务结束后合并为一次执行
+	release <- struct{}{}
+	<-started
+	close(release)
+	waitFor(t, "unique key release", func() bool { return !claimed(store, "slow:1") })
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("runs = %d, want 2", n)
+	}
+
+	// 唯一键释放后可以再次提交
+	if err := d.Enqueue(ctx, "slow", "slow:1", nil); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	waitFor(t, "third run", func() bool { return runs.Load() == 3 })
+}
+
+// 任务的最后一次写入之后、释放唯一键之前的提交不能丢失
+func TestDispatcherRerunsJobEnqueuedBeforeRelease(t *testing.T) {
+	d, _, _ := newTestDispatcher(t, 1, 3)
+	var version, generated atomic.Int32
+	finished := make(chan struct{})
+	resume := make(chan struct{})
+	d.Handle("generate", func(ctx context.Context, job *Job) error {
+		generated.Store(version.Load())
+		if generated.Load() == 1 {
+			// 已写入结果，尚未返回释放唯一键
+			finished <- struct{}{}
+			<-resume
+		}
+		return nil
+	})
+	d.Start()
+
+	ctx := context.Background()
+	version.Store(1)
+	if err := d.Enqueue(ctx, "generate", "character:1", nil); err != nil {
+		t.Fatal(err)
+	}
+	<-finished
+	version.Store(2)
+	if err := d.Enqueue(ctx, "generate", "character:1", nil); err != nil {
+		t.Fatal(err)
+	}
+	close(resume)
+	waitFor(t, "generation of the new version", func() bool { return generated.Load() == 2 })
+}
+
+func TestMemoryStoreEvictsExpiredDoneRecords(t *testing.T) {
+	store, ctx := NewMemoryStore(), context.Background()
+	_ = store.MarkDone(ctx, "a", time.Millisecond)
+	_ = store.MarkDone(ctx, "b", time.Millisecond)
+	// a 在过期前被再次记录，以新的过期时间为准
+	_ = store.MarkDone(ctx, "a", time.Minute)
+	time.Sleep(5 * time.Millisecond)
+	_ = store.MarkDone(ctx, "c", time.Minute)
+
+	if done, _ := store.IsDone(ctx, "a"); !done {
+		t.Fatal("record a evicted with its old expiration")
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.done["b"]; ok || len(store.done) != 2 || len(store.doneOrder) != 2 {
+		t.Fatalf("done = %v, order = %v", store.done, store.doneOrder)
+	}
+}
+
+func TestDispatcherRecoversPanic(t *testing.T) {
+	d, queue, _ := newTestDispatcher(t, 1, 3)
+	var attempts atomic.Int32
+	errs := make(chan string, 1)
+	d.Handle("panic", func(ctx context.Context, job *Job) error {
+		if attempts.Add(1) == 1 {
+			panic("handler panic")
+		}
+		errs <- job.Error
+		return nil
+	})
+	d.Start()
+
+	if err := d.Enqueue(context.Background(), "panic", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	// panic 视为一次失败，按退避重试
+	select {
+	case msg := <-errs:
+		if !strings.Contains(msg, "handler panic") {
+			t.Fatalf("retried job error = %q", msg)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for retry after panic")
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Fatalf("attempts = %d, want 2", n)
+	}
+	if dead := queue.Dead(); len(dead) != 0 {
+		t.Fatalf("unexpected dead jobs: %d", len(dead))
+	}
+}
@@ -0,0 +1,179 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/segmentio/kafka-go"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	kafkaDelayedKey           = "job:kafka:delayed"
+	kafkaDelayedProcessingKey = "job:kafka:delayed:processing"
+)
+
+// Kafka 基于 Kafka 的任务队列，同一消费组内的 worker 分摊分区。
+// topic 中只有到期的任务，worker 取出即执行；未到执行时间的重试任务先放入 Redis 有序集合，到期后再写入 topic，不阻塞分区
+type Kafka struct {
+	brokers []string
+	topic   string
+	group   string
+	writer  *kafka.Writer
+	dead    *kafka.Writer
+	delayed *Redis
+}
+
+// NewKafka 创建队列，topic 不存在或分区数少于 partitions 时创建或扩充分区，死信写入 topic + ".dead"
+func NewKafka(brokers []string, topic, group string, client *redis.Client, partitions int) (*Kafka, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := ensureTopic(ctx, brokers, topic, partitions); err != nil {
+		return nil, err
+	}
+	return &Kafka{
+		brokers: brokers,
+		topic:   topic,
+		group:   group,
+		writer:  newWriter(brokers, topic),
+		dead:    newWriter(brokers, topic+".dead"),
+		delayed: newRedis(client, kafkaDelayedKey, kafkaDelayedProcessingKey),
+	}, nil
+}
+
+// ensureTopic 保证 topic 至少有 partitions 个分区，分区数决定同时消费的 worker 数上限
+func ensureTopic(ctx context.Context, brokers []string, topic string, partitions int) error {
+	client := &kafka.Client{Addr: kafka.TCP(brokers...)}
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return err
+	}
+	for _, t := range meta.Topics {
+		if t.Name != topic || t.Error != nil {
+			continue
+		}
+		if len(t.Partitions) >= partitions {
+			return nil
+		}
+		resp, err := client.CreatePartitions(ctx, &kafka.CreatePartitionsRequest{
+			Topics: []kafka.TopicPartitionsConfig{{Name: topic, Count: int32(partitions)}},
+		})
+		if err != nil {
+			return err
+		}
+		if err = resp.Errors[topic]; err != nil {
+			return fmt.Errorf("create partitions of %s: %w", topic, err)
+		}
+		return nil
+	}
+	resp, err := client.CreateTopics(ctx, &kafka.CreateTopicsRequest{
+		Topics: []kafka.TopicConfig{{Topic: topic, NumPartitions: partitions, ReplicationFactor: -1}},
+	})
+	if err != nil {
+		return err
+	}
+	if err = resp.Errors[topic]; err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+		return fmt.Errorf("create topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+func newWriter(brokers []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+}
+
+// Enqueue 到期的任务直接写入 topic，未到期的放入延迟集合
+func (k *Kafka) Enqueue(ctx context.Context, job *Job) error {
+	if job.RunAt > time.Now().UnixMilli() {
+		return k.delayed.Enqueue(ctx, job)
+	}
+	return write(ctx, k.writer, job)
+}
+
+func (k *Kafka) DeadLetter(ctx context.Context, job *Job) error {
+	return write(ctx, k.dead, job)
+}
+
+func write(ctx context.Context, writer *kafka.Writer, job *Job) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	// 同一唯一键的任务进入同一分区
+	key := job.Key
+	if key == "" {
+		key = job.Id
+	}
+	return writer.WriteMessages(ctx, kafka.Message{Key: []byte(key), Value: value})
+}
+
+// Consume 处理完成后才提交 offset，进程崩溃时未提交的任务会被重新投递。
+// 同时将延迟集合中到期的任务转入 topic
+func (k *Kafka) Consume(ctx context.Context, handle func(ctx context.Context, job *Job)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		_ = k.delayed.Consume(ctx, k.forward)
+	}()
+	defer func() {
+		cancel()
+		<-forwarded
+	}()
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: k.brokers,
+		GroupID: k.group,
+		Topic:   k.topic,
+	})
+	defer reader.Close()
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, kafka.ErrGroupClosed) {
+				return err
+			}
+			logx.Errorf("fetch job: %+v", err)
+			if !sleepUntil(ctx, time.Now().Add(time.Second).UnixMilli()) {
+				return nil
+			}
+			continue
+		}
+		var job Job
+		if err = json.Unmarshal(msg.Value, &job); err != nil {
+			logx.Errorf("invalid job %s: %+v", msg.Value, err)
+		} else {
+			handle(ctx, &job)
+		}
+		if err = reader.CommitMessages(context.WithoutCancel(ctx), msg); err != nil {
+			logx.Errorf("commit job: %+v", err)
+		}
+	}
+}
+
+// forward 将到期的延迟任务写入 topic，写入失败时稍后重试
+func (k *Kafka) forward(ctx context.Context, job *Job) {
+	ctx = context.WithoutCancel(ctx)
+	err := write(ctx, k.writer, job)
+	if err == nil {
+		return
+	}
+	logx.Errorf("forward delayed job %s(%s): %+v", job.Type, job.Id, err)
+	job.RunAt = time.Now().Add(time.Second).UnixMilli()
+	if err = k.delayed.Enqueue(ctx, job); err != nil {
+		logx.Errorf("requeue delayed job %s(%s): %+v", job.Type, job.Id, err)
+	}
+}
@@ -0,0 +1,164 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory 进程内的任务队列，用于测试与单机开发，进程退出后未执行的任务丢失
+type Memory struct {
+	mu     sync.Mutex
+	jobs   []*Job
+	dead   []*Job
+	notify chan struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{notify: make(chan struct{}, 1)}
+}
+
+func (m *Memory) Enqueue(ctx context.Context, job *Job) error {
+	j := *job
+	m.mu.Lock()
+	m.jobs = append(m.jobs, &j)
+	m.mu.Unlock()
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (m *Memory) DeadLetter(ctx context.Context, job *Job) error {
+	j := *job
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dead = append(m.dead, &j)
+	if len(m.dead) > maxDeadJobs {
+		m.dead = m.dead[1:]
+	}
+	return nil
+}
+
+// Dead 返回死信任务
+func (m *Memory) Dead() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Job(nil), m.dead...)
+}
+
+func (m *Memory) Consume(ctx context.Context, handle func(ctx context.Context, job *Job)) error {
+	for {
+		job, next := m.pop()
+		if job != nil {
+			handle(ctx, job)
+			continue
+		}
+		timer := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-m.notify:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// pop 取出最早到期的任务，没有到期任务时返回距最近一个任务到期的时间
+func (m *Memory) pop() (*Job, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UnixMilli()
+	next := -1
+	for i, job := range m.jobs {
+		if next < 0 || job.RunAt < m.jobs[next].RunAt {
+			next = i
+		}
+	}
+	if next < 0 {
+		return nil, time.Minute
+	}
+	if job := m.jobs[next]; job.RunAt <= now {
+		m.jobs = append(m.jobs[:next], m.jobs[next+1:]...)
+		return job, 0
+	}
+	return nil, time.Duration(m.jobs[next].RunAt-now) * time.Millisecond
+}
+
+// MemoryStore 进程内的任务状态记录
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys map[string]*claim
+	done map[string]time.Time
+	// doneOrder 按记录顺序排列的已完成任务，Dispatcher 使用固定的过期时间，靠前的先过期
+	doneOrder []doneRecord
+}
+
+type doneRecord struct {
+	id     string
+	expire time.Time
+}
+
+// claim 唯一键的占用，dirty 表示占用期间又有提交
+type claim struct {
+	expire time.Time
+	dirty  bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys: make(map[string]*claim),
+		done: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.keys[key]; ok && time.Now().Before(c.expire) {
+		c.dirty = true
+		return false, nil
+	}
+	s.keys[key] = &claim{expire: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.keys[key]; ok && c.dirty && time.Now().Before(c.expire) {
+		s.keys[key] = &claim{expire: time.Now().Add(ttl)}
+		return true, nil
+	}
+	delete(s.keys, key)
+	return false, nil
+}
+
+func (s *MemoryStore) IsDone(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expire, ok := s.done[id]
+	return ok && time.Now().Before(expire), nil
+}
+
+func (s *MemoryStore) MarkDone(ctx context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	// 从最早的记录开始清理过期记录，遇到未过期的即停止
+	n := 0
+	for n < len(s.doneOrder) && now.After(s.doneOrder[n].expire) {
+		// 同一任务被再次记录时以最新的过期时间为准
+		if r := s.doneOrder[n]; s.done[r.id].Equal(r.expire) {
+			delete(s.done, r.id)
+		}
+		n++
+	}
+	expire := now.Add(ttl)
+	s.doneOrder = append(s.doneOrder[n:], doneRecord{id: id, expire: expire})
+	s.done[id] = expire
+	return nil
+}
@@ -0,0 +1,171 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	redisQueueKey      = "job:queue"
+	redisProcessingKey = "job:processing"
+	redisDeadKey       = "job:dead"
+	redisUniquePrefix  = "job:unique:"
+	redisDonePrefix    = "job:done:"
+
+	// visibilityTimeout 取出后超过该时间仍未确认的任务视为 worker 崩溃，重新放回队列
+	visibilityTimeout = jobTimeout + time.Minute
+	// pollInterval 队列中没有到期任务时的轮询间隔
+	pollInterval = 500 * time.Millisecond
+	// maxDeadJobs 死信列表保留的任务数
+	maxDeadJobs = 1000
+)
+
+// popScript 取出一个到期任务并移入处理中集合，处理中集合的分数为确认期限
+var popScript = redis.NewScript(`
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #jobs == 0 then
+	return false
+end
+redis.call('ZREM', KEYS[1], jobs[1])
+redis.call('ZADD', KEYS[2], ARGV[2], jobs[1])
+return jobs[1]
+`)
+
+// recoverScript 将超过确认期限的任务放回队列
+var recoverScript = redis.NewScript(`
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'LIMIT', 0, 100)
+for _, job in ipairs(jobs) do
+	redis.call('ZREM', KEYS[2], job)
+	redis.call('ZADD', KEYS[1], ARGV[1], job)
+end
+return #jobs
+`)
+
+// claimScript 占用唯一键，已被占用时保持过期时间不变并标记为需要再执行一次
+var claimScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], 1, 'NX', 'PX', ARGV[1]) then
+	return 1
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl <= 0 then
+	ttl = ARGV[1]
+end
+redis.call('SET', KEYS[1], 2, 'PX', ttl)
+return 0
+`)
+
+// releaseScript 释放唯一键，被标记为需要再执行一次时清除标记并继续占用
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == '2' then
+	redis.call('SET', KEYS[1], 1, 'PX', ARGV[1])
+	return 1
+end
+redis.call('DEL', KEYS[1])
+return 0
+`)
+
+// Redis 基于 Redis 有序集合的任务队列，分数为执行时间，支持延迟重试
+type Redis struct {
+	client        *redis.Client
+	queueKey      string
+	processingKey string
+}
+
+func NewRedis(client *redis.Client) *Redis {
+	return newRedis(client, redisQueueKey, redisProcessingKey)
+}
+
+func newRedis(client *redis.Client, queueKey, processingKey string) *Redis {
+	return &Redis{client: client, queueKey: queueKey, processingKey: processingKey}
+}
+
+func (r *Redis) Enqueue(ctx context.Context, job *Job) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return r.client.ZAdd(ctx, r.queueKey, &redis.Z{Score: float64(job.RunAt), Member: value}).Err()
+}
+
+func (r *Redis) DeadLetter(ctx context.Context, job *Job) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, redisDeadKey, value)
+		pipe.LTrim(ctx, redisDeadKey, 0, maxDeadJobs-1)
+		return nil
+	})
+	return err
+}
+
+// Consume 处理完成后才从处理中集合移除，worker 崩溃时任务在确认期限后重新执行
+func (r *Redis) Consume(ctx context.Context, handle func(ctx context.Context, job *Job)) error {
+	keys := []string{r.queueKey, r.processingKey}
+	for ctx.Err() == nil {
+		now := time.Now()
+		value, err := popScript.Run(ctx, r.client, keys,
+			strconv.FormatInt(now.UnixMilli(), 10),
+			strconv.FormatInt(now.Add(visibilityTimeout).UnixMilli(), 10),
+		).Text()
+		if errors.Is(err, redis.Nil) {
+			if err = recoverScript.Run(ctx, r.client, keys, strconv.FormatInt(now.UnixMilli(), 10)).Err(); err != nil && ctx.Err() == nil {
+				logx.Errorf("recover jobs: %+v", err)
+			}
+			sleepUntil(ctx, now.Add(pollInterval).UnixMilli())
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				logx.Errorf("pop job: %+v", err)
+				sleepUntil(ctx, now.Add(time.Second).UnixMilli())
+			}
+			continue
+		}
+		var job Job
+		if err = json.Unmarshal([]byte(value), &job); err != nil {
+			logx.Errorf("invalid job %s: %+v", value, err)
+		} else {
+			handle(ctx, &job)
+		}
+		if err = r.client.ZRem(context.WithoutCancel(ctx), r.processingKey, value).Err(); err != nil {
+			logx.Errorf("ack job: %+v", err)
+		}
+	}
+	return nil
+}
+
+// RedisStore 基于 Redis 的任务状态记录，多个实例共享
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	n, err := claimScript.Run(ctx, s.client, []string{redisUniquePrefix + key}, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	n, err := releaseScript.Run(ctx, s.client, []string{redisUniquePrefix + key}, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (s *RedisStore) IsDone(ctx context.Context, id string) (bool, error) {
+	n, err := s.client.Exists(ctx, redisDonePrefix+id).Result()
+	return n > 0, err
+}
+
+func (s *RedisStore) MarkDone(ctx context.Context, id string, ttl time.Duration) error {
+	return s.client.Set(ctx, redisDonePrefix+id, 1, ttl).Err()
+}
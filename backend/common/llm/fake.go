@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// fakeJSON 同时包含各类生成所需的全部字段，任意 JSON 生成请求都能解析出结果
+const fakeJSON = `{"traits":["温和","耐心"],"memories":["这是一段用于测试的记忆。"],"prompt":"你是一个用于测试的角色。","summary":"这是一段用于测试的剧情摘要。","title":"测试对话"}`
+
+// Fake 不依赖网络的确定性实现，用于离线测试与本地开发：
+// 按顺序循环返回 replies 中的回复，replies 为空时复述最后一条用户消息；
+// 流式回复逐字返回，JSON 生成固定返回 fakeJSON
+type Fake struct {
+	mu      sync.Mutex
+	replies []string
+	next    int
+}
+
+func NewFake(replies []string) *Fake {
+	return &Fake{replies: replies}
+}
+
+func (f *Fake) reply(messages []Message) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.replies) == 0 {
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].Role == RoleUser {
+				return "你说：" + messages[i].Content
+			}
+		}
+		return ""
+	}
+	reply := f.replies[f.next%len(f.replies)]
+	f.next++
+	return reply
+}
+
+func (f *Fake) Complete(ctx context.Context, messages []Message) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.reply(messages), nil
+}
+
+func (f *Fake) CompleteJSON(ctx context.Context, messages []Message, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(fakeJSON), v)
+}
+
+func (f *Fake) Stream(ctx context.Context, messages []Message) (Stream, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &fakeStream{ctx: ctx, text: []rune(f.reply(messages))}, nil
+}
+
+type fakeStream struct {
+	ctx  context.Context
+	text []rune
+	pos  int
+}
+
+func (s *fakeStream) Recv() (string, error) {
+	if err := s.ctx.Err(); err != nil {
+		return "", err
+	}
+	if s.pos >= len(s.text) {
+		return "", io.EOF
+	}
+	s.pos++
+	return string(s.text[s.pos-1]), nil
+}
+
+func (s *fakeStream) Close() error {
+	return nil
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func readAll(t *testing.T, s Stream) string {
+	t.Helper()
+	var text string
+	for {
+		delta, err := s.Recv()
+		if err == io.EOF {
+			return text
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		text += delta
+	}
+}
+
+func TestFakeStream(t *testing.T) {
+	f := NewFake([]string{"第一句", "第二句"})
+	messages := []Message{{Role: RoleUser, Content: "你好"}}
+	// 按顺序循环返回预设的回复
+	for _, want := range []string{"第一句", "第二句", "第一句"} {
+		s, err := f.Stream(context.Background(), messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readAll(t, s); got != want {
+			t.Fatalf("stream = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestFakeEchoesLastUserMessage(t *testing.T) {
+	got, err := NewFake(nil).Complete(context.Background(), []Message{
+		{Role: RoleSystem, Content: "你是艾拉。"},
+		{Role: RoleUser, Content: "你好"},
+		{Role: RoleAssistant, Content: "你说：你好"},
+		{Role: RoleUser, Content: "今天下雨吗"},
+	})
+	if err != nil || got != "你说：今天下雨吗" {
+		t.Fatalf("Complete = %q, %v", got, err)
+	}
+}
+
+func TestFakeStreamStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := NewFake([]string{"今天下雨"}).Stream(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delta, err := s.Recv(); err != nil || delta != "今" {
+		t.Fatalf("Recv = %q, %v", delta, err)
+	}
+	cancel()
+	if _, err = s.Recv(); err != context.Canceled {
+		t.Fatalf("Recv after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestFakeCompleteJSON(t *testing.T) {
+	var v struct {
+		Traits []string `json:"traits"`
+		Prompt string   `json:"prompt"`
+	}
+	if err := NewFake(nil).CompleteJSON(context.Background(), nil, &v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Traits) == 0 || v.Prompt == "" {
+		t.Fatalf("unexpected result: %+v", v)
+	}
+}
@@ -3,15 +3,17 @@ package llm
 import (
 	"context"
 	_ "embed"
-	"encoding/json"
 	"fmt"
-	"github.com/sashabaranov/go-openai"
 	"github.com/zeromicro/go-zero/core/logx"
-	"log"
-	"regexp"
 	"time"
 )
 
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
 const (
 	jsonPrompt = "You are a helpful assistant that must output strict JSON when instructed,without any additional text."
 )
@@ -28,6 +30,15 @@ var systemPromptTpl string
 //go:embed prompts/generate_opening.tpl
 var generateOpening string
 
+//go:embed prompts/extract_memory.tpl
+var extractMemoryTpl string
+
+//go:embed prompts/summarize.tpl
+var summarizeTpl string
+
+//go:embed prompts/title.tpl
+var titleTpl string
+
 type personality struct {
 	Traits []string `json:"traits"`
 }
@@ -40,63 +51,54 @@ type systemPrompt struct {
 	Prompt string `json:"prompt"`
 }
 
-type Client struct {
-	client *openai.Client
-	model  string
+type summary struct {
+	Summary string `json:"summary"`
 }
 
-func New(apiKey, model, baseUrl string) *Client {
-	config := openai.DefaultConfig(apiKey)
-	config.BaseURL = baseUrl
-	cli := openai.NewClientWithConfig(config)
-	return &Client{
-		client: cli,
-		model:  model,
-	}
+type title struct {
+	Title string `json:"title"`
 }
 
-var jsonRe = regexp.MustCompile(`(?s){.*?}`)
+// Message 一条对话消息，Role 为 system / user / assistant
+type Message struct {
+	Role    string
+	Content string
+}
 
-func extractJson(s string) (string, error) {
-	match := jsonRe.FindString(s)
-	if len(match) == 0 {
-		return "", fmt.Errorf("no json found")
-	}
-	return match, nil
+// Provider 大模型服务
+type Provider interface {
+	// Complete 生成完整回复
+	Complete(ctx context.Context, messages []Message) (string, error)
+	// CompleteJSON 生成回复并将其中的 JSON 解析到 v
+	CompleteJSON(ctx context.Context, messages []Message, v interface{}) error
+	// Stream 流式生成回复，ctx 被取消时流随之中断
+	Stream(ctx context.Context, messages []Message) (Stream, error)
 }
 
-func (c *Client) call(message string, system string) (string, error) {
-	client := c.client
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// Stream 一次流式回复
+type Stream interface {
+	// Recv 返回下一段增量文本，生成结束时返回 io.EOF
+	Recv() (string, error)
+	Close() error
+}
 
-	resp, err := client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model:       c.model,
-			Temperature: 0.7,
-			MaxTokens:   800,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: system,
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: message,
-				},
-			},
-		},
-	)
-	if err != nil {
-		return "", err
-	}
+type Client struct {
+	provider Provider
+}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no choices from llm")
+func New(provider Provider) *Client {
+	return &Client{
+		provider: provider,
 	}
+}
 
-	return resp.Choices[0].Message.Content, nil
+func (c *Client) callJSON(message string, system string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return c.provider.CompleteJSON(ctx, []Message{
+		{Role: RoleSystem, Content: system},
+		{Role: RoleUser, Content: message},
+	}, v)
 }
 
 const (
@@ -107,19 +109,11 @@ func (c *Client) GeneratePersonality(description, background string) ([]string,
 	prompt := fmt.Sprintf(personalityTpl, description, background)
 	var result personality
 	for i := 0; i < retryTimes; i++ {
-		raw, err := c.call(prompt, jsonPrompt)
-		fmt.Println(raw)
-		if err != nil {
+		if err := c.callJSON(prompt, jsonPrompt, &result); err != nil {
 			logx.Error(err)
 			continue
 		}
-		raw, err = extractJson(raw)
-		if err != nil {
-			continue
-		}
-		if json.Unmarshal([]byte(raw), &result) == nil {
-			return result.Traits, nil
-		}
+		return result.Traits, nil
 	}
 	return nil, fmt.Errorf("failed to generate personality after retries")
 }
@@ -129,21 +123,61 @@ func (c *Client) GenerateInitialMemory(background string) ([]string, error) {
 
 	var result initialMemory
 	for i := 0; i < retryTimes; i++ {
-		raw, err := c.call(prompt, jsonPrompt)
-		fmt.Println(raw)
-		if err != nil {
+		if err := c.callJSON(prompt, jsonPrompt, &result); err != nil {
 			logx.Error(err)
 			continue
 		}
-		raw, err = extractJson(raw)
-		if err != nil {
+		return result.Memories, nil
+	}
+	return nil, fmt.Errorf("failed to generate initial memory after retries")
+}
+
+// ExtractMemories 从一段对话中提炼关于用户的长期记忆，dialogue 为逐行的「说话人: 内容」
+func (c *Client) ExtractMemories(characterName, dialogue string) ([]string, error) {
+	prompt := fmt.Sprintf(extractMemoryTpl, characterName, dialogue)
+
+	var result initialMemory
+	for i := 0; i < retryTimes; i++ {
+		if err := c.callJSON(prompt, jsonPrompt, &result); err != nil {
+			logx.Error(err)
 			continue
 		}
-		if json.Unmarshal([]byte(raw), &result) == nil {
-			return result.Memories, nil
+		return result.Memories, nil
+	}
+	return nil, fmt.Errorf("failed to extract memories after retries")
+}
+
+// Summarize 将已有摘要与新的对话合并为新的剧情摘要，已有摘要可以为空
+func (c *Client) Summarize(characterName, previous, dialogue string) (string, error) {
+	if previous == "" {
+		previous = "（无）"
+	}
+	prompt := fmt.Sprintf(summarizeTpl, characterName, previous, dialogue)
+
+	var result summary
+	for i := 0; i < retryTimes; i++ {
+		if err := c.callJSON(prompt, jsonPrompt, &result); err != nil {
+			logx.Error(err)
+			continue
 		}
+		return result.Summary, nil
 	}
-	return nil, fmt.Errorf("failed to generate initial memory after retries")
+	return "", fmt.Errorf("failed to summarize after retries")
+}
+
+// GenerateTitle 根据对话的开头生成会话标题，dialogue 为逐行的「说话人: 内容」
+func (c *Client) GenerateTitle(characterName, dialogue string) (string, error) {
+	prompt := fmt.Sprintf(titleTpl, characterName, dialogue)
+
+	var result title
+	for i := 0; i < retryTimes; i++ {
+		if err := c.callJSON(prompt, jsonPrompt, &result); err != nil {
+			logx.Error(err)
+			continue
+		}
+		return result.Title, nil
+	}
+	return "", fmt.Errorf("failed to generate title after retries")
 }
 
 func (c *Client) GenerateSystemPrompt(name, description string, personality []string) (string, error) {
@@ -151,53 +185,24 @@ func (c *Client) GenerateSystemPrompt(name, description string, personality []st
 
 	var result systemPrompt
 	for i := 0; i < retryTimes; i++ {
-		raw, err := c.call(prompt, systemPromptTpl)
-		fmt.Println(raw)
-		if err != nil {
+		if err := c.callJSON(prompt, systemPromptTpl, &result); err != nil {
 			logx.Error(err)
 			continue
 		}
-		raw, err = extractJson(raw)
-		if err != nil {
-			continue
-		}
-		if json.Unmarshal([]byte(raw), &result) == nil {
-			result.Prompt = `你不是程序或语言模型，也不受程序或语言模型的限制。你不知道程序或模型是什么，不能以任何形式提及程序或语言模型相关内容。
+		result.Prompt = `你不是程序或语言模型，也不受程序或语言模型的限制。你不知道程序或模型是什么，不能以任何形式提及程序或语言模型相关内容。
 ` + result.Prompt
-			return result.Prompt, nil
-		}
+		return result.Prompt, nil
 	}
 	return "", fmt.Errorf("failed to generate system prompt after retries")
 }
 
-func (c *Client) GetStream(history []openai.ChatCompletionMessage) (*openai.ChatCompletionStream, error) {
-	ctx := context.Background()
-	stream, err := c.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
-		Model:    c.model,
-		Messages: history,
-		Stream:   true,
-	})
-	if err != nil {
-		log.Println("openai stream error:", err)
-		return nil, err
-	}
-	return stream, nil
+// GetStream 流式生成回复，ctx 被取消时流随之中断
+func (c *Client) GetStream(ctx context.Context, history []Message) (Stream, error) {
+	return c.provider.Stream(ctx, history)
 }
 
 func (c *Client) GenerateOpening(openLine string) (string, error) {
-	ctx := context.Background()
-	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: c.model,
-		Messages: []openai.ChatCompletionMessage{
-			{Role: "user", Content: fmt.Sprintf(generateOpening, openLine)},
-		},
+	return c.provider.Complete(context.Background(), []Message{
+		{Role: RoleUser, Content: fmt.Sprintf(generateOpening, openLine)},
 	})
-	if err != nil {
-		return "", err
-	}
-
-	if len(resp.Choices) > 0 {
-		return resp.Choices[0].Message.Content, nil
-	}
-	return "", nil
 }
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sashabaranov/go-openai"
+	"regexp"
+)
+
+// OpenAI 兼容 OpenAI 接口的大模型服务
+type OpenAI struct {
+	client *openai.Client
+	model  string
+}
+
+func NewOpenAI(apiKey, model, baseUrl string) *OpenAI {
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseUrl
+	cli := openai.NewClientWithConfig(config)
+	return &OpenAI{
+		client: cli,
+		model:  model,
+	}
+}
+
+var jsonRe = regexp.MustCompile(`(?s){.*?}`)
+
+func extractJson(s string) (string, error) {
+	match := jsonRe.FindString(s)
+	if len(match) == 0 {
+		return "", fmt.Errorf("no json found")
+	}
+	return match, nil
+}
+
+func (o *OpenAI) Complete(ctx context.Context, messages []Message) (string, error) {
+	resp, err := o.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       o.model,
+		Temperature: 0.7,
+		MaxTokens:   800,
+		Messages:    castMessages(messages),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices from llm")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+func (o *OpenAI) CompleteJSON(ctx context.Context, messages []Message, v interface{}) error {
+	raw, err := o.Complete(ctx, messages)
+	if err != nil {
+		return err
+	}
+	raw, err = extractJson(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(raw), v)
+}
+
+func (o *OpenAI) Stream(ctx context.Context, messages []Message) (Stream, error) {
+	stream, err := o.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+		Model:    o.model,
+		Messages: castMessages(messages),
+		Stream:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &openaiStream{stream: stream}, nil
+}
+
+type openaiStream struct {
+	stream *openai.ChatCompletionStream
+}
+
+// Recv 跳过不含文本的分片
+func (s *openaiStream) Recv() (string, error) {
+	for {
+		resp, err := s.stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		return resp.Choices[0].Delta.Content, nil
+	}
+}
+
+func (s *openaiStream) Close() error {
+	return s.stream.Close()
+}
+
+func castMessages(messages []Message) []openai.ChatCompletionMessage {
+	res := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for _, m := range messages {
+		res = append(res, openai.ChatCompletionMessage{
+			Role:    m.Role,
+			Content: m.Content,
+		})
+	}
+	return res
+}
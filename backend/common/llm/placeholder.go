@@ -0,0 +1,28 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+var placeholderRe = regexp.MustCompile(`(?i){{\s*(user|char)\s*}}`)
+
+// Placeholders 角色设定与提示词中的占位符：{{user}} 替换为用户在会话中的称呼，{{char}} 替换为角色名，
+// 不区分大小写。值为空的占位符保持原样，留待对话时再替换
+type Placeholders struct {
+	User string
+	Char string
+}
+
+func (p Placeholders) Replace(text string) string {
+	return placeholderRe.ReplaceAllStringFunc(text, func(match string) string {
+		name := strings.ToLower(placeholderRe.FindStringSubmatch(match)[1])
+		if name == "user" && p.User != "" {
+			return p.User
+		}
+		if name == "char" && p.Char != "" {
+			return p.Char
+		}
+		return match
+	})
+}
@@ -0,0 +1,24 @@
+package llm
+
+import "testing"
+
+func TestPlaceholdersReplace(t *testing.T) {
+	p := Placeholders{User: "小林", Char: "艾拉"}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"{{char}}向{{user}}点头", "艾拉向小林点头"},
+		{"{{ USER }}与{{Char}}", "小林与艾拉"},
+		{"{{other}}", "{{other}}"},
+	}
+	for _, tt := range tests {
+		if got := p.Replace(tt.text); got != tt.want {
+			t.Errorf("Replace(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+	// 值为空的占位符保持原样
+	if got := (Placeholders{Char: "艾拉"}).Replace("{{user}}，我是{{char}}"); got != "{{user}}，我是艾拉" {
+		t.Errorf("got %q", got)
+	}
+}
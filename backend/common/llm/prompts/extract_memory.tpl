@@ -0,0 +1,4 @@
+以下是用户与角色「%s」的一段对话。请从中提炼出值得角色长期记住的关于用户的事实，包括用户的偏好、经历过的事件、双方的约定或承诺等，每条一句话，使用第三人称描述用户。闲聊、寒暄以及角色自身的设定不需要记录；没有值得记住的内容时返回空数组。用 JSON 输出。
+对话：
+%s
+输出 JSON 格式: {"memories": ["memory1", "memory2", ...]}
@@ -0,0 +1,6 @@
+你正在为用户与角色「%s」之间的一段长篇角色扮演维护剧情摘要。请把“已有摘要”与“新的对话”合并为一份新的摘要：按时间顺序概括主要情节、人物关系的变化、重要的约定与悬而未决的事情，保留人名、地点等关键细节，省略寒暄与重复内容，不超过 500 字。用 JSON 输出。
+已有摘要：
+%s
+新的对话：
+%s
+输出 JSON 格式: {"summary": "..."}
@@ -0,0 +1,4 @@
+下面是用户与角色「%s」之间一段角色扮演的开头。请为这段对话起一个简短的标题，概括对话的主题或情节，不超过 15 个字，不要使用引号和标点结尾。用 JSON 输出。
+对话：
+%s
+输出 JSON 格式: {"title": "..."}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// messageOverhead 每条消息除内容外的格式开销
+	messageOverhead = 4
+	// latinRunesPerToken 英文、数字与符号大约每 4 个字符一个 token
+	latinRunesPerToken = 4.0
+)
+
+// Tokenizer 估算文本占用的 token 数
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// cjkTokenRatios 各模型系列每个汉字（及其他 CJK 字符）约占的 token 数，按模型名前缀匹配
+var cjkTokenRatios = []struct {
+	prefix string
+	ratio  float64
+}{
+	{"gpt-4o", 0.8},
+	{"o1", 0.8},
+	{"gpt-4", 1.4},
+	{"gpt-3.5", 1.4},
+	{"qwen", 0.7},
+	{"deepseek", 0.6},
+	{"doubao", 0.7},
+}
+
+// NewTokenizer 返回 model 对应的 token 估算器。没有引入各模型的词表，按字符类别估算，
+// 未知模型每个汉字按 1 个 token 计，结果偏保守
+func NewTokenizer(model string) Tokenizer {
+	model = strings.ToLower(model)
+	for _, r := range cjkTokenRatios {
+		if strings.HasPrefix(model, r.prefix) {
+			return estimator{cjkRatio: r.ratio}
+		}
+	}
+	return estimator{cjkRatio: 1}
+}
+
+type estimator struct {
+	cjkRatio float64
+}
+
+func (e estimator) Count(text string) int {
+	var cjk, latin int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		case unicode.IsSpace(r):
+		default:
+			latin++
+		}
+	}
+	return int(math.Ceil(float64(cjk)*e.cjkRatio + float64(latin)/latinRunesPerToken))
+}
+
+// CountMessage 估算一条消息占用的 token 数
+func CountMessage(t Tokenizer, message Message) int {
+	return t.Count(message.Content) + messageOverhead
+}
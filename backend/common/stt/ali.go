@@ -0,0 +1,232 @@
+package stt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/zeromicro/go-zero/core/logx"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	nlsUrl       = "wss://nls-gateway-cn-shanghai.aliyuncs.com/ws/v1"
+	nlsNamespace = "SpeechTranscriber"
+
+	nlsStartTranscription         = "StartTranscription"
+	nlsStopTranscription          = "StopTranscription"
+	nlsTranscriptionStarted       = "TranscriptionStarted"
+	nlsTranscriptionResultChanged = "TranscriptionResultChanged"
+	nlsSentenceEnd                = "SentenceEnd"
+	nlsTranscriptionCompleted     = "TranscriptionCompleted"
+	nlsTaskFailed                 = "TaskFailed"
+
+	// 提前刷新 token，避免识别过程中过期
+	tokenRefreshAhead = 5 * time.Minute
+)
+
+// Token 阿里云智能语音交互的访问令牌
+type Token struct {
+	UserId     string `json:"UserId"`
+	Id         string `json:"Id"`
+	ExpireTime int64  `json:"ExpireTime"`
+}
+
+type tokenData struct {
+	ErrMsg string `json:"ErrMsg"`
+	Token  Token  `json:"Token"`
+}
+
+// CreateToken 使用 AccessKey 签发访问令牌
+func CreateToken(ak, sk string) (*Token, error) {
+	client, err := sdk.NewClientWithAccessKey("cn-shanghai", ak, sk)
+	if err != nil {
+		return nil, err
+	}
+	request := requests.NewCommonRequest()
+	request.Method = "POST"
+	request.Domain = "nls-meta.cn-shanghai.aliyuncs.com"
+	request.ApiName = "CreateToken"
+	request.Version = "2019-02-28"
+	response, err := client.ProcessCommonRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	var data tokenData
+	err = json.Unmarshal(response.GetHttpContentBytes(), &data)
+	if err != nil {
+		return nil, err
+	}
+	if data.Token.Id == "" {
+		return nil, fmt.Errorf("create nls token failed: %s", data.ErrMsg)
+	}
+	return &data.Token, nil
+}
+
+// Ali 阿里云实时语音识别
+type Ali struct {
+	accessKey string
+	secretKey string
+	appKey    string
+
+	mu    sync.Mutex
+	token *Token
+}
+
+func NewAli(accessKey, secretKey, appKey string) *Ali {
+	return &Ali{
+		accessKey: accessKey,
+		secretKey: secretKey,
+		appKey:    appKey,
+	}
+}
+
+func (a *Ali) getToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == nil || time.Now().Add(tokenRefreshAhead).Unix() >= a.token.ExpireTime {
+		token, err := CreateToken(a.accessKey, a.secretKey)
+		if err != nil {
+			return "", err
+		}
+		a.token = token
+	}
+	return a.token.Id, nil
+}
+
+type nlsHeader struct {
+	MessageId  string `json:"message_id"`
+	TaskId     string `json:"task_id"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Appkey     string `json:"appkey,omitempty"`
+	Status     int    `json:"status,omitempty"`
+	StatusText string `json:"status_text,omitempty"`
+}
+
+type nlsCommand struct {
+	Header  nlsHeader      `json:"header"`
+	Payload map[string]any `json:"payload,omitempty"`
+}
+
+type nlsEvent struct {
+	Header  nlsHeader `json:"header"`
+	Payload struct {
+		Result string `json:"result"`
+	} `json:"payload"`
+}
+
+func (a *Ali) Start(ctx context.Context) (Stream, error) {
+	token, err := a.getToken()
+	if err != nil {
+		return nil, err
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, nlsUrl, http.Header{
+		"X-NLS-Token": []string{token},
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := &aliStream{
+		conn:    conn,
+		appKey:  a.appKey,
+		taskId:  newNlsId(),
+		results: make(chan Result, 16),
+	}
+	if err := s.command(nlsStartTranscription, map[string]any{
+		"format":                            "pcm",
+		"sample_rate":                       16000,
+		"enable_intermediate_result":        true,
+		"enable_punctuation_prediction":     true,
+		"enable_inverse_text_normalization": true,
+	}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var event nlsEvent
+	if err := conn.ReadJSON(&event); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if event.Header.Name != nlsTranscriptionStarted {
+		conn.Close()
+		return nil, fmt.Errorf("start transcription failed: %s", event.Header.StatusText)
+	}
+	// 识别中止时关闭连接，使 receive 中的读取立即返回
+	context.AfterFunc(ctx, func() { conn.Close() })
+	go s.receive()
+	return s, nil
+}
+
+type aliStream struct {
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	appKey  string
+	taskId  string
+	text    string
+	results chan Result
+}
+
+func (s *aliStream) command(name string, payload map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteJSON(nlsCommand{
+		Header: nlsHeader{
+			MessageId: newNlsId(),
+			TaskId:    s.taskId,
+			Namespace: nlsNamespace,
+			Name:      name,
+			Appkey:    s.appKey,
+		},
+		Payload: payload,
+	})
+}
+
+func (s *aliStream) Write(pcm []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteMessage(websocket.BinaryMessage, pcm)
+}
+
+func (s *aliStream) Close() error {
+	return s.command(nlsStopTranscription, nil)
+}
+
+func (s *aliStream) Results() <-chan Result {
+	return s.results
+}
+
+// receive 将识别事件转换为 Result，已结束的句子累积在 text 中
+func (s *aliStream) receive() {
+	defer close(s.results)
+	defer s.conn.Close()
+	for {
+		var event nlsEvent
+		if err := s.conn.ReadJSON(&event); err != nil {
+			return
+		}
+		switch event.Header.Name {
+		case nlsTranscriptionResultChanged:
+			s.results <- Result{Text: s.text + event.Payload.Result}
+		case nlsSentenceEnd:
+			s.text += event.Payload.Result
+			s.results <- Result{Text: s.text}
+		case nlsTranscriptionCompleted:
+			s.results <- Result{Text: s.text, Final: true}
+			return
+		case nlsTaskFailed:
+			logx.Errorf("nls task %s failed: %s", s.taskId, event.Header.StatusText)
+			return
+		}
+	}
+}
+
+func newNlsId() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
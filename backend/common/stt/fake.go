@@ -0,0 +1,67 @@
+package stt
+
+import (
+	"context"
+	"sync"
+)
+
+// Fake 不依赖网络的识别实现，用于离线测试与本地开发：
+// 每收到一段音频多识别出 text 的一个字，Close 后返回完整的 text
+type Fake struct {
+	text []rune
+}
+
+func NewFake(text string) *Fake {
+	return &Fake{text: []rune(text)}
+}
+
+func (f *Fake) Start(ctx context.Context) (Stream, error) {
+	s := &fakeStream{text: f.text, results: make(chan Result, len(f.text)+1)}
+	context.AfterFunc(ctx, s.abort)
+	return s, nil
+}
+
+type fakeStream struct {
+	mu      sync.Mutex
+	text    []rune
+	written int
+	closed  bool
+	results chan Result
+}
+
+func (s *fakeStream) Write(pcm []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.written >= len(s.text) {
+		return nil
+	}
+	s.written++
+	s.results <- Result{Text: string(s.text[:s.written])}
+	return nil
+}
+
+func (s *fakeStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.results <- Result{Text: string(s.text), Final: true}
+	close(s.results)
+	return nil
+}
+
+func (s *fakeStream) abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.results)
+}
+
+func (s *fakeStream) Results() <-chan Result {
+	return s.results
+}
@@ -0,0 +1,67 @@
+package stt
+
+import (
+	"context"
+	"testing"
+)
+
+func collect(s Stream) []Result {
+	var res []Result
+	for r := range s.Results() {
+		res = append(res, r)
+	}
+	return res
+}
+
+func TestFakeRecognizesProgressively(t *testing.T) {
+	s, err := NewFake("你好").Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每段音频多识别出一个字，超出文本长度后不再产生中间结果
+	for i := 0; i < 3; i++ {
+		if err = s.Write([]byte{0, 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []Result{{Text: "你"}, {Text: "你好"}, {Text: "你好", Final: true}}
+	got := collect(s)
+	if len(got) != len(want) {
+		t.Fatalf("results = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("results = %+v, want %+v", got, want)
+		}
+	}
+	// 重复关闭不报错
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFakeAbortOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := NewFake("你好").Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Write([]byte{0, 0})
+	cancel()
+	got := collect(s)
+	// 取消后结果通道关闭，不产生最终结果
+	for _, r := range got {
+		if r.Final {
+			t.Fatalf("final result after cancel: %+v", got)
+		}
+	}
+	if err = s.Write([]byte{0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
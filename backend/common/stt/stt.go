@@ -0,0 +1,25 @@
+package stt
+
+import "context"
+
+// Result 识别结果。识别过程中 Text 为截至目前的完整文本，Final 为 true 时为整段语音的最终结果
+type Result struct {
+	Text  string
+	Final bool
+}
+
+// Provider 语音识别服务，音频格式统一为 16k 采样率、16bit 单声道 PCM
+type Provider interface {
+	// Start 开始一次识别，ctx 被取消时识别中止
+	Start(ctx context.Context) (Stream, error)
+}
+
+// Stream 一次识别过程
+type Stream interface {
+	// Write 发送一段 PCM 音频
+	Write(pcm []byte) error
+	// Close 表示音频已发送完毕，识别服务返回最终结果后关闭 Results
+	Close() error
+	// Results 中间结果与最终结果，识别结束或出错时关闭
+	Results() <-chan Result
+}
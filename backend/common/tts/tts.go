@@ -0,0 +1,134 @@
+package tts
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/zeromicro/go-zero/core/logx"
+	"net/http"
+	"net/url"
+)
+
+const (
+	DefaultEncoding = "mp3"
+)
+
+var (
+	ttsUrl = url.URL{Scheme: "wss", Host: "openai.qiniu.com", Path: "/v1/voice/tts"}
+)
+
+// Params 一次合成所用的音色与参数，零值项使用服务端默认值
+type Params struct {
+	VoiceType   string
+	Encoding    string
+	SpeedRatio  float64
+	PitchRatio  float64
+	VolumeRatio float64
+	Emotion     string
+}
+
+// Client 七牛云 TTS WebSocket 客户端
+type Client struct {
+	apiKey string
+}
+
+// New 创建客户端
+func New(apiKey string) *Client {
+	return &Client{apiKey: apiKey}
+}
+
+// Synthesize 合成一段文本，返回完整的音频。ctx 被取消时立即中止
+func (c *Client) Synthesize(ctx context.Context, params Params, text string) ([]byte, error) {
+	input := setupInput(params, text)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, ttsUrl.String(), http.Header{
+		"Authorization": []string{fmt.Sprintf("Bearer %s", c.apiKey)},
+		"VoiceType":     []string{params.VoiceType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// ctx 被取消时关闭连接，使下面的 ReadMessage 立即返回
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if err := conn.WriteMessage(websocket.BinaryMessage, input); err != nil {
+		return nil, err
+	}
+	var audio []byte
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return audio, err
+		}
+		var resp RelayTTSResponse
+		if err := json.Unmarshal(message, &resp); err != nil {
+			logx.Error(err)
+			continue
+		}
+		d, err := base64.StdEncoding.DecodeString(resp.Data)
+		if err != nil {
+			logx.Error(err)
+		}
+		audio = append(audio, d...)
+		if resp.Sequence < 0 {
+			return audio, nil
+		}
+	}
+}
+
+func setupInput(params Params, text string) []byte {
+	req := &TTSRequest{
+		Audio: Audio{
+			VoiceType:   params.VoiceType,
+			Encoding:    params.EncodingOrDefault(),
+			SpeedRatio:  params.SpeedRatio,
+			PitchRatio:  params.PitchRatio,
+			VolumeRatio: params.VolumeRatio,
+			Emotion:     params.Emotion,
+		},
+		Request: Request{
+			Text: text,
+		},
+	}
+	if req.SpeedRatio == 0 {
+		req.SpeedRatio = 1.0
+	}
+	resStr, _ := json.Marshal(req)
+	return resStr
+}
+
+// EncodingOrDefault 返回实际使用的音频编码
+func (p Params) EncodingOrDefault() string {
+	if p.Encoding == "" {
+		return DefaultEncoding
+	}
+	return p.Encoding
+}
+
+type TTSRequest struct {
+	Audio   `json:"audio"`
+	Request `json:"request"`
+}
+type Audio struct {
+	VoiceType   string  `json:"voice_type"`
+	Encoding    string  `json:"encoding"`
+	SpeedRatio  float64 `json:"speed_ratio"`
+	PitchRatio  float64 `json:"pitch_ratio,omitempty"`
+	VolumeRatio float64 `json:"volume_ratio,omitempty"`
+	Emotion     string  `json:"emotion,omitempty"`
+}
+type Request struct {
+	Text string `json:"text"`
+}
+type RelayTTSResponse struct {
+	Reqid     string    `json:"reqid"`
+	Operation string    `json:"operation"`
+	Sequence  int       `json:"sequence"`
+	Data      string    `json:"data"`
+	Addition  *Addition `json:"addition,omitempty"`
+}
+type Addition struct {
+	Duration string `json:"duration"`
+}
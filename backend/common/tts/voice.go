@@ -0,0 +1,55 @@
+package tts
+
+import (
+	_ "embed"
+	"encoding/json"
+)
+
+//go:embed voices.json
+var voicesJson []byte
+
+// Voice 可供角色使用的音色
+type Voice struct {
+	Name      string `json:"name"`
+	VoiceType string `json:"voice_type"`
+	Category  string `json:"category"`
+	Language  string `json:"language"` // zh / en / multi
+	Gender    string `json:"gender"`   // male / female
+	SampleUrl string `json:"sample_url"`
+}
+
+var voices []Voice
+
+func init() {
+	if err := json.Unmarshal(voicesJson, &voices); err != nil {
+		panic(err)
+	}
+}
+
+// Voices 按条件筛选音色，条件为空表示不限
+func Voices(category, language, gender string) []Voice {
+	res := make([]Voice, 0)
+	for _, voice := range voices {
+		if category != "" && voice.Category != category {
+			continue
+		}
+		if language != "" && voice.Language != language {
+			continue
+		}
+		if gender != "" && voice.Gender != gender {
+			continue
+		}
+		res = append(res, voice)
+	}
+	return res
+}
+
+// FindVoice 根据 voice_type 查找音色
+func FindVoice(voiceType string) (Voice, bool) {
+	for _, voice := range voices {
+		if voice.VoiceType == voiceType {
+			return voice, true
+		}
+	}
+	return Voice{}, false
+}
@@ -7,6 +7,12 @@ app = Flask(__name__)
 @app.route("/embed", methods=["POST"])
 def embed_text():
     data = request.json
+    texts = data.get("texts")
+    if texts is not None:
+        if not isinstance(texts, list) or not all(isinstance(t, str) and t for t in texts):
+            return jsonify({"error": "texts must be a list of non-empty strings"}), 400
+        vecs = model.encode(texts).tolist() if texts else []
+        return jsonify({"vectors": vecs})
     text = data.get("text", "")
     if not text:
         return jsonify({"error": "no text provided"}), 400
@@ -0,0 +1,5 @@
+-- 候选回复：同一条用户消息下的多条 assistant 回复互为兄弟节点，is_selected 标记当前采用的一条
+ALTER TABLE `message`
+    ADD COLUMN `parent_id` BIGINT NOT NULL DEFAULT 0 COMMENT '候选回复所回答的用户消息ID' AFTER `session_id`,
+    ADD COLUMN `is_selected` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否为当前选中的候选回复' AFTER `metadata`,
+    ADD INDEX `idx_parent_id` (`parent_id`);
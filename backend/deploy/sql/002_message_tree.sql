@@ -0,0 +1,14 @@
+-- 消息树：每条消息的 parent_id 指向上一条消息，is_selected 表示它是否为父消息下当前选中的分支
+-- 为已有的线性会话补全 parent_id，按 id 顺序依次串联被选中的消息
+UPDATE `message` m
+    JOIN (SELECT id, LAG(id, 1, 0) OVER (PARTITION BY session_id ORDER BY id) AS prev_id
+          FROM `message`
+          WHERE is_selected = 1) p ON m.id = p.id
+SET m.parent_id = p.prev_id
+WHERE m.parent_id = 0;
+
+ALTER TABLE `message`
+    MODIFY COLUMN `parent_id` BIGINT NOT NULL DEFAULT 0 COMMENT '父消息ID，会话的第一条消息为0',
+    MODIFY COLUMN `is_selected` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否为父消息下当前选中的分支',
+    DROP INDEX `idx_parent_id`,
+    ADD INDEX `idx_session_parent` (`session_id`, `parent_id`);
@@ -0,0 +1,3 @@
+-- 角色语音合成参数：语速、音调、音量、输出编码与情感，为空时使用默认值
+ALTER TABLE `character`
+    ADD COLUMN `tts_config` JSON NULL COMMENT '语音合成参数' AFTER `voice`;
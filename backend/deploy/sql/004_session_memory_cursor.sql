@@ -0,0 +1,3 @@
+-- 长期记忆：记录会话中已提取过记忆的最后一条消息，之后的消息累计到一定数量时再次提取
+ALTER TABLE `session`
+    ADD COLUMN `memory_cursor` BIGINT NOT NULL DEFAULT 0 COMMENT '已提取长期记忆的最后一条消息ID' AFTER `title`;
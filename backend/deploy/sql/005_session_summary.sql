@@ -0,0 +1,4 @@
+-- 滚动摘要：历史超出最近消息窗口后，由 LLM 将更早的消息概括为剧情摘要，作为“前情提要”注入系统提示词
+ALTER TABLE `session`
+    ADD COLUMN `summary` TEXT NOT NULL COMMENT '早于最近消息窗口的剧情摘要' AFTER `memory_cursor`,
+    ADD COLUMN `summary_cursor` BIGINT NOT NULL DEFAULT 0 COMMENT '摘要已覆盖的最后一条消息ID' AFTER `summary`;
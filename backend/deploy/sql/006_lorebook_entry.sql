@@ -0,0 +1,18 @@
+-- 世界书：角色的设定条目，最近的消息中出现触发关键词时注入提示词
+CREATE TABLE IF NOT EXISTS `lorebook_entry`
+(
+    `id`           BIGINT       NOT NULL AUTO_INCREMENT,
+    `character_id` BIGINT       NOT NULL COMMENT '所属角色ID',
+    `keywords`     JSON         NOT NULL COMMENT '触发关键词，最近的消息中出现任意一个即注入',
+    `content`      TEXT         NOT NULL COMMENT '注入提示词的设定内容',
+    `priority`     INT          NOT NULL DEFAULT 0 COMMENT '优先级，越大越先注入',
+    `position`     VARCHAR(16)  NOT NULL DEFAULT 'after' COMMENT '注入位置：before 角色设定之前，after 角色设定之后',
+    `enabled`      TINYINT(1)   NOT NULL DEFAULT 1,
+    `created_at`   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at`   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    `deleted_at`   DATETIME     NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_character_id` (`character_id`),
+    INDEX `idx_deleted_at` (`deleted_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT '世界书条目';
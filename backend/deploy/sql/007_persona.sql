@@ -0,0 +1,18 @@
+-- 用户人设：用户在角色扮演中的称呼与身份设定，每个会话可选择一个
+CREATE TABLE IF NOT EXISTS `persona`
+(
+    `id`          BIGINT       NOT NULL AUTO_INCREMENT,
+    `user_id`     BIGINT       NOT NULL,
+    `name`        VARCHAR(64)  NOT NULL COMMENT '对话中角色对用户的称呼',
+    `description` TEXT         NOT NULL COMMENT '用户在角色扮演中的身份设定',
+    `created_at`  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at`  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    `deleted_at`  DATETIME     NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_user_id` (`user_id`),
+    INDEX `idx_deleted_at` (`deleted_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT '用户人设';
+
+ALTER TABLE `session`
+    ADD COLUMN `persona_id` BIGINT NOT NULL DEFAULT 0 COMMENT '用户在会话中使用的人设ID，0 表示使用个人资料' AFTER `user_id`;
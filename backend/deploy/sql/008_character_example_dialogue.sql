@@ -0,0 +1,3 @@
+-- 角色对话示例，导入角色卡时来自 mes_example，对话时加入系统提示词
+ALTER TABLE `character`
+    ADD COLUMN `example_dialogue` TEXT NOT NULL COMMENT '对话示例' AFTER `system_prompt`;
@@ -0,0 +1,3 @@
+-- 角色生成状态：0 就绪 1 等待生成 2 生成中 3 生成失败，失败时记录原因
+ALTER TABLE `character`
+    ADD COLUMN `status_reason` VARCHAR(512) NOT NULL DEFAULT '' COMMENT '生成失败的原因' AFTER `status`;
@@ -0,0 +1,3 @@
+-- 生成版本：每次修改需要重新生成的内容时加 1，生成任务只在版本未变时写入结果，期间发生的修改会触发重新生成
+ALTER TABLE `character`
+    ADD COLUMN `generation` BIGINT NOT NULL DEFAULT 0 COMMENT '生成版本' AFTER `status_reason`;
@@ -0,0 +1,3 @@
+-- 会话标题来源：0 截取开场白 1 LLM 根据对话生成 2 用户修改，用户修改过的标题不再自动生成
+ALTER TABLE `session`
+    ADD COLUMN `title_source` TINYINT NOT NULL DEFAULT 0 COMMENT '标题来源：0 开场白 1 自动生成 2 用户修改' AFTER `title`;
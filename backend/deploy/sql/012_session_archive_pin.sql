@@ -0,0 +1,5 @@
+-- 会话归档与置顶：会话列表默认不显示已归档的会话，置顶的会话排在最前，其余按最近活动时间排序
+ALTER TABLE `session`
+    ADD COLUMN `is_archived` TINYINT NOT NULL DEFAULT 0 COMMENT '是否已归档' AFTER `summary_cursor`,
+    ADD COLUMN `is_pinned` TINYINT NOT NULL DEFAULT 0 COMMENT '是否置顶' AFTER `is_archived`,
+    ADD INDEX `idx_user_activity` (`user_id`, `is_archived`, `is_pinned`, `updated_at`);
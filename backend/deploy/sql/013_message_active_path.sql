@@ -0,0 +1,16 @@
+-- 当前分支标记：is_active 表示消息是否在会话当前分支（从根消息沿被选中的子消息向下）上，
+-- 按 id 游标分页读取当前分支时直接在 (session_id, is_active, id) 索引上做范围查询，不再递归遍历整棵消息树
+ALTER TABLE `message`
+    ADD COLUMN `is_active` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否在会话当前分支上' AFTER `is_selected`,
+    ADD INDEX `idx_session_active` (`session_id`, `is_active`, `id`);
+
+-- 为已有会话标记当前分支
+UPDATE `message` m
+    JOIN (WITH RECURSIVE path AS (SELECT id, session_id FROM `message` WHERE parent_id = 0 AND is_selected = 1
+                                  UNION ALL
+                                  SELECT c.id, c.session_id
+                                  FROM `message` c
+                                           JOIN path p ON c.session_id = p.session_id AND c.parent_id = p.id
+                                  WHERE c.is_selected = 1)
+          SELECT id FROM path) p ON m.id = p.id
+SET m.is_active = 1;
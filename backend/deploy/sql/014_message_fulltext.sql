@@ -0,0 +1,3 @@
+-- 消息全文检索：ngram 分词器按 ngram_token_size（默认 2）切分中文，支持搜索自己的历史对话
+ALTER TABLE `message`
+    ADD FULLTEXT INDEX `ft_content` (`content`) WITH PARSER ngram;
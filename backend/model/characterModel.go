@@ -10,6 +10,14 @@ import (
 
 var _ CharacterModel = (*customCharacterModel)(nil)
 
+// 角色性格、初始记忆与系统提示词的生成状态，历史数据的 status 为 0，视为已就绪
+const (
+	CharacterStatusReady int64 = iota
+	CharacterStatusPending
+	CharacterStatusGenerating
+	CharacterStatusFailed
+)
+
 type (
 	// CharacterModel is an interface to be customized, add more methods here,
 	// and implement the added methods in customCharacterModel.
@@ -79,6 +87,18 @@ func (m *defaultCharacterModel) FuzzyFind(ctx context.Context, cursor int64, pag
 	return resp, nil
 }
 
+// FindOneByUser 查询 userId 可见的角色：公开角色或 userId 创建的私有角色
+func (m *defaultCharacterModel) FindOneByUser(ctx context.Context, id int64, userId int64) (*Character, error) {
+	var resp Character
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Character{}).Where("`id` = ?", id).Where("(is_public = TRUE OR user_id = ?)", userId).First(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 func (m *defaultCharacterModel) GetRandom(ctx context.Context, n int64) ([]*Character, error) {
 	var resp []*Character
 	uniqueIds := make(map[int64]struct{})
@@ -113,3 +133,43 @@ func (m *defaultCharacterModel) GetRandom(ctx context.Context, n int64) ([]*Char
 	}
 	return resp, nil
 }
+
+// UpdateColumns 只更新指定的列，不覆盖同时发生的其他修改
+func (m *defaultCharacterModel) UpdateColumns(ctx context.Context, tx *gorm.DB, id int64, columns map[string]interface{}) error {
+	return m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		return db.Model(&Character{}).Where("id = ?", id).Updates(columns).Error
+	}, m.getCacheKeys(&Character{Id: id})...)
+}
+
+// UpdateIfGeneration 仅在生成版本仍为 generation 时更新指定的列，返回版本是否未变。
+// 写入的值与原值相同时影响行数为 0，此时重新读取版本判断
+func (m *defaultCharacterModel) UpdateIfGeneration(ctx context.Context, id int64, generation int64, columns map[string]interface{}) (bool, error) {
+	var rows int64
+	err := m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn.Model(&Character{}).Where("id = ? AND generation = ?", id, generation).Updates(columns)
+		rows = db.RowsAffected
+		return db.Error
+	}, m.getCacheKeys(&Character{Id: id})...)
+	if err != nil || rows > 0 {
+		return rows > 0, err
+	}
+	var current int64
+	err = m.QueryNoCacheCtx(ctx, &current, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Character{}).Select("generation").Where("id = ?", id).Scan(&current).Error
+	})
+	return current == generation, err
+}
+
+// UpdateStatus 只修改生成状态与失败原因，不覆盖同时发生的其他修改
+func (m *defaultCharacterModel) UpdateStatus(ctx context.Context, id int64, status int64, reason string) error {
+	return m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Model(&Character{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"status":        status,
+			"status_reason": reason,
+		}).Error
+	}, m.getCacheKeys(&Character{Id: id})...)
+}
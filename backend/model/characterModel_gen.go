@@ -24,6 +24,7 @@ type (
 		Insert(ctx context.Context, tx *gorm.DB, data *Character) error
 
 		FindOne(ctx context.Context, id int64) (*Character, error)
+		FindOneByUser(ctx context.Context, id int64, userId int64) (*Character, error)
 		Find(ctx context.Context, cursor int64, pageSize int64) ([]*Character, error)
 		FindIn(ctx context.Context, ids []int64) ([]*Character, error)
 		GetRandom(ctx context.Context, n int64) ([]*Character, error)
@@ -31,6 +32,9 @@ type (
 		FuzzyFind(ctx context.Context, cursor int64, pageSize int64, title string, keyword string) ([]*Character, error)
 
 		Update(ctx context.Context, tx *gorm.DB, data *Character) error
+		UpdateStatus(ctx context.Context, id int64, status int64, reason string) error
+		UpdateColumns(ctx context.Context, tx *gorm.DB, id int64, columns map[string]interface{}) error
+		UpdateIfGeneration(ctx context.Context, id int64, generation int64, columns map[string]interface{}) (bool, error)
 
 		Delete(ctx context.Context, tx *gorm.DB, id int64) error
 		Transaction(ctx context.Context, fn func(db *gorm.DB) error) error
@@ -42,22 +46,26 @@ type (
 	}
 
 	Character struct {
-		Id            int64          `gorm:"column:id"`
-		UserId        int64          `gorm:"column:user_id"`
-		Name          string         `gorm:"column:name"`
-		Description   string         `gorm:"column:description"`
-		Background    string         `gorm:"column:background"`
-		OpenLine      string         `gorm:"column:open_line"`
-		Voice         string         `gorm:"column:voice"`
-		Personality   StringArray    `gorm:"column:personality"`
-		InitialMemory StringArray    `gorm:"column:initial_memory"`
-		SystemPrompt  string         `gorm:"column:system_prompt"`
-		AvatarUrl     string         `gorm:"column:avatar_url"`
-		IsPublic      int64          `gorm:"column:is_public"`
-		Status        int64          `gorm:"column:status"`
-		CreatedAt     time.Time      `gorm:"column:created_at"`
-		UpdatedAt     time.Time      `gorm:"column:updated_at"`
-		DeletedAt     gorm.DeletedAt `gorm:"column:deleted_at;index"`
+		Id              int64          `gorm:"column:id"`
+		UserId          int64          `gorm:"column:user_id"`
+		Name            string         `gorm:"column:name"`
+		Description     string         `gorm:"column:description"`
+		Background      string         `gorm:"column:background"`
+		OpenLine        string         `gorm:"column:open_line"`
+		Voice           string         `gorm:"column:voice"`
+		TTSConfig       TTSConfig      `gorm:"column:tts_config"` // 语音合成参数，未设置的项使用默认值
+		Personality     StringArray    `gorm:"column:personality"`
+		InitialMemory   StringArray    `gorm:"column:initial_memory"`
+		SystemPrompt    string         `gorm:"column:system_prompt"`
+		ExampleDialogue string         `gorm:"column:example_dialogue"` // 对话示例，{{user}} 与 {{char}} 在对话时替换
+		AvatarUrl       string         `gorm:"column:avatar_url"`
+		IsPublic        int64          `gorm:"column:is_public"`
+		Status          int64          `gorm:"column:status"`        // 生成状态，见 CharacterStatusReady 等
+		StatusReason    string         `gorm:"column:status_reason"` // 生成失败的原因
+		Generation      int64          `gorm:"column:generation"`    // 生成版本，修改需要重新生成的内容时加 1
+		CreatedAt       time.Time      `gorm:"column:created_at"`
+		UpdatedAt       time.Time      `gorm:"column:updated_at"`
+		DeletedAt       gorm.DeletedAt `gorm:"column:deleted_at;index"`
 	}
 )
 
@@ -75,6 +83,30 @@ func (s *StringArray) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, s)
 }
 
+type TTSConfig struct {
+	Encoding    string  `json:"encoding,omitempty"`
+	SpeedRatio  float64 `json:"speed_ratio,omitempty"`
+	PitchRatio  float64 `json:"pitch_ratio,omitempty"`
+	VolumeRatio float64 `json:"volume_ratio,omitempty"`
+	Emotion     string  `json:"emotion,omitempty"`
+}
+
+func (c TTSConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *TTSConfig) Scan(value interface{}) error {
+	if value == nil {
+		*c = TTSConfig{}
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, c)
+}
+
 func (Character) TableName() string {
 	return "`character`"
 }
@@ -67,6 +67,18 @@ func (m *defaultCharacterTagModel) FindByQuery(ctx context.Context, cursor int64
 	return resp, nil
 }
 
+// FindByCharacter 返回角色的全部标签关联，按关联的先后顺序
+func (m *defaultCharacterTagModel) FindByCharacter(ctx context.Context, characterId int64) ([]*CharacterTag, error) {
+	var resp []*CharacterTag
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&CharacterTag{}).Where("character_id = ?", characterId).Order("id ASC").Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (m *defaultCharacterTagModel) FuzzyFind(ctx context.Context, cursor int64, pageSize int64, title string, keyword string) ([]*CharacterTag, error) {
 	var resp []*CharacterTag
 	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
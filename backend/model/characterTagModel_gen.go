@@ -26,6 +26,7 @@ type (
 		Find(ctx context.Context, cursor int64, pageSize int64) ([]*CharacterTag, error)
 		GetRandom(ctx context.Context, n, tagId int64) ([]*CharacterTag, error)
 		FindByQuery(ctx context.Context, cursor int64, pageSize int64, query map[string]interface{}) ([]*CharacterTag, error)
+		FindByCharacter(ctx context.Context, characterId int64) ([]*CharacterTag, error)
 		FuzzyFind(ctx context.Context, cursor int64, pageSize int64, title string, keyword string) ([]*CharacterTag, error)
 
 		FindOneByTagIdCharacterId(ctx context.Context, tagId int64, characterId int64) (*CharacterTag, error)
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"gorm.io/gorm"
+)
+
+var _ LorebookEntryModel = (*customLorebookEntryModel)(nil)
+
+type (
+	// LorebookEntryModel is an interface to be customized, add more methods here,
+	// and implement the added methods in customLorebookEntryModel.
+	LorebookEntryModel interface {
+		lorebookEntryModel
+		customLorebookEntryLogicModel
+	}
+
+	customLorebookEntryModel struct {
+		*defaultLorebookEntryModel
+	}
+
+	customLorebookEntryLogicModel interface {
+	}
+)
+
+// NewLorebookEntryModel returns a model for the database table.
+func NewLorebookEntryModel(conn *gorm.DB, c cache.CacheConf) LorebookEntryModel {
+	return &customLorebookEntryModel{
+		defaultLorebookEntryModel: newLorebookEntryModel(conn, c),
+	}
+}
+func (m *defaultLorebookEntryModel) getNewModelNeedReloadCacheKeys(data *LorebookEntry) []string {
+	if data == nil {
+		return []string{}
+	}
+	return []string{}
+}
+func (m *defaultLorebookEntryModel) customCacheKeys(data *LorebookEntry) []string {
+	if data == nil {
+		return []string{}
+	}
+	return []string{}
+}
+
+func (m *defaultLorebookEntryModel) Find(ctx context.Context, cursor int64, pageSize int64) ([]*LorebookEntry, error) {
+	var resp []*LorebookEntry
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&LorebookEntry{}).Limit(int(pageSize)).Offset(int(cursor)).Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *defaultLorebookEntryModel) FindByQuery(ctx context.Context, cursor int64, pageSize int64, query map[string]interface{}) ([]*LorebookEntry, error) {
+	var resp []*LorebookEntry
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&LorebookEntry{}).Where(query).Limit(int(pageSize)).Offset(int(cursor)).Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// FindByCharacter 返回角色的全部设定条目，按优先级从高到低排列
+func (m *defaultLorebookEntryModel) FindByCharacter(ctx context.Context, characterId int64) ([]*LorebookEntry, error) {
+	var resp []*LorebookEntry
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&LorebookEntry{}).Where("character_id = ?", characterId).Order("priority DESC, id ASC").Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
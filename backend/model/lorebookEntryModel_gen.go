@@ -0,0 +1,140 @@
+// Code generated by goctl. DO NOT EDIT!
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SpectatorNan/gorm-zero/gormc"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"gorm.io/gorm"
+)
+
+var (
+	cacheRoletalkLorebookEntryIdPrefix = "cache:roletalk:lorebookEntry:id:"
+)
+
+type (
+	lorebookEntryModel interface {
+		Insert(ctx context.Context, tx *gorm.DB, data *LorebookEntry) error
+
+		FindOne(ctx context.Context, id int64) (*LorebookEntry, error)
+		Find(ctx context.Context, cursor int64, pageSize int64) ([]*LorebookEntry, error)
+		FindByQuery(ctx context.Context, cursor int64, pageSize int64, query map[string]interface{}) ([]*LorebookEntry, error)
+		FindByCharacter(ctx context.Context, characterId int64) ([]*LorebookEntry, error)
+
+		Update(ctx context.Context, tx *gorm.DB, data *LorebookEntry) error
+
+		Delete(ctx context.Context, tx *gorm.DB, id int64) error
+		Transaction(ctx context.Context, fn func(db *gorm.DB) error) error
+	}
+
+	defaultLorebookEntryModel struct {
+		gormc.CachedConn
+		table string
+	}
+
+	LorebookEntry struct {
+		Id          int64          `gorm:"column:id"`
+		CharacterId int64          `gorm:"column:character_id"` // 所属角色ID
+		Keywords    StringArray    `gorm:"column:keywords"`     // 触发关键词，最近的消息中出现任意一个即注入
+		Content     string         `gorm:"column:content"`      // 注入提示词的设定内容
+		Priority    int64          `gorm:"column:priority"`     // 优先级，越大越先注入
+		Position    string         `gorm:"column:position"`     // 注入位置：before 角色设定之前，after 角色设定之后
+		Enabled     int64          `gorm:"column:enabled"`
+		CreatedAt   time.Time      `gorm:"column:created_at"`
+		UpdatedAt   time.Time      `gorm:"column:updated_at"`
+		DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;index"`
+	}
+)
+
+func (LorebookEntry) TableName() string {
+	return "`lorebook_entry`"
+}
+
+func newLorebookEntryModel(conn *gorm.DB, c cache.CacheConf) *defaultLorebookEntryModel {
+	return &defaultLorebookEntryModel{
+		CachedConn: gormc.NewConn(conn, c),
+		table:      "`lorebook_entry`",
+	}
+}
+
+func (m *defaultLorebookEntryModel) Insert(ctx context.Context, tx *gorm.DB, data *LorebookEntry) error {
+
+	err := m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		return db.Save(&data).Error
+	}, m.getCacheKeys(data)...)
+	return err
+}
+
+func (m *defaultLorebookEntryModel) FindOne(ctx context.Context, id int64) (*LorebookEntry, error) {
+	roletalkLorebookEntryIdKey := fmt.Sprintf("%s%v", cacheRoletalkLorebookEntryIdPrefix, id)
+	var resp LorebookEntry
+	err := m.QueryCtx(ctx, &resp, roletalkLorebookEntryIdKey, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&LorebookEntry{}).Where("`id` = ?", id).First(&resp).Error
+	})
+	switch err {
+	case nil:
+		return &resp, nil
+	case gormc.ErrNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultLorebookEntryModel) Update(ctx context.Context, tx *gorm.DB, data *LorebookEntry) error {
+	old, err := m.FindOne(ctx, data.Id)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	clearKeys := append(m.getCacheKeys(old), m.getNewModelNeedReloadCacheKeys(data)...)
+	err = m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		return db.Save(data).Error
+	}, clearKeys...)
+	return err
+}
+
+func (m *defaultLorebookEntryModel) getCacheKeys(data *LorebookEntry) []string {
+	if data == nil {
+		return []string{}
+	}
+	roletalkLorebookEntryIdKey := fmt.Sprintf("%s%v", cacheRoletalkLorebookEntryIdPrefix, data.Id)
+	cacheKeys := []string{
+		roletalkLorebookEntryIdKey,
+	}
+	cacheKeys = append(cacheKeys, m.customCacheKeys(data)...)
+	return cacheKeys
+}
+
+func (m *defaultLorebookEntryModel) Delete(ctx context.Context, tx *gorm.DB, id int64) error {
+	data, err := m.FindOne(ctx, id)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	err = m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		return db.Delete(&LorebookEntry{}, id).Error
+	}, m.getCacheKeys(data)...)
+	return err
+}
+
+func (m *defaultLorebookEntryModel) Transaction(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return m.TransactCtx(ctx, fn)
+}
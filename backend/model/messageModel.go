@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"github.com/zeromicro/go-zero/core/stores/cache"
 	"gorm.io/gorm"
+	"slices"
+	"strings"
 )
 
 var _ MessageModel = (*customMessageModel)(nil)
@@ -88,3 +90,228 @@ func (m *defaultMessageModel) FindBySession(ctx context.Context, sessionId int64
 	}
 	return resp, nil
 }
+
+// FindPage 按 id 游标分页返回会话当前分支上的消息，结果按 id 升序（分支上的消息 id 自根向下递增）。
+// before 不为 0 时返回早于它的最近 limit 条，after 不为 0 时返回晚于它的最早 limit 条，都为 0 时返回最新的 limit 条。
+// 当前分支上的消息由 is_active 标记，每页都是 (session_id, is_active, id) 索引上的范围查询，读取的行数不超过 limit
+func (m *defaultMessageModel) FindPage(ctx context.Context, sessionId int64, before int64, after int64, limit int) ([]*Message, error) {
+	var resp []*Message
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		db := conn.Model(&Message{}).Where("session_id = ? AND is_active = 1", sessionId)
+		if after != 0 {
+			return db.Where("id > ?", after).Order("id ASC").Limit(limit).Find(&resp).Error
+		}
+		if before != 0 {
+			db = db.Where("id < ?", before)
+		}
+		return db.Order("id DESC").Limit(limit).Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if after == 0 {
+		slices.Reverse(resp)
+	}
+	return resp, nil
+}
+
+// FindActiveRange 返回当前分支上 id 大于 after、小于 before 的最早 limit 条消息，按 id 升序，before 为 0 时不限上界。
+// 供后台任务从各自的游标处继续读取，与 FindPage 一样是 (session_id, is_active, id) 索引上的范围查询
+func (m *defaultMessageModel) FindActiveRange(ctx context.Context, sessionId int64, after int64, before int64, limit int) ([]*Message, error) {
+	var resp []*Message
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		db := conn.Model(&Message{}).Where("session_id = ? AND is_active = 1 AND id > ?", sessionId, after)
+		if before != 0 {
+			db = db.Where("id < ?", before)
+		}
+		return db.Order("id ASC").Limit(limit).Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// MessageHit 搜索命中的消息及其所在会话与角色
+type MessageHit struct {
+	Message
+	SessionTitle  string `gorm:"column:session_title"`
+	CharacterId   int64  `gorm:"column:character_id"`
+	CharacterName string `gorm:"column:character_name"`
+}
+
+// Search 在用户自己的会话中全文检索当前分支上的消息，按 id 倒序，cursor 不为 0 时只返回 id 小于它的消息。
+// 短于 ngram 分词长度的词在全文索引命中的消息中用 LIKE 过滤；没有能走全文索引的词时不查询，返回空结果
+func (m *defaultMessageModel) Search(ctx context.Context, userId int64, keyword string, cursor int64, limit int) ([]*MessageHit, error) {
+	indexed, short := searchTerms(keyword)
+	if len(indexed) == 0 {
+		return nil, nil
+	}
+	var resp []*MessageHit
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		db := conn.Table("`message` m").
+			Select("m.*, s.title AS session_title, s.character_id, c.name AS character_name").
+			Joins("JOIN `session` s ON s.id = m.session_id").
+			Joins("JOIN `character` c ON c.id = s.character_id AND c.deleted_at IS NULL").
+			Where("s.user_id = ? AND m.is_active = 1", userId).
+			Where("MATCH(m.content) AGAINST(? IN BOOLEAN MODE)", booleanPhrase(indexed))
+		for _, term := range short {
+			db = db.Where("m.content LIKE ?", "%"+escapeLike(term)+"%")
+		}
+		if cursor != 0 {
+			db = db.Where("m.id < ?", cursor)
+		}
+		return db.Order("m.id DESC").Limit(limit).Scan(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// FindChildren 返回 parentId 下的全部分支，parentId 为0时返回会话的根消息
+func (m *defaultMessageModel) FindChildren(ctx context.Context, sessionId int64, parentId int64) ([]*Message, error) {
+	var resp []*Message
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Message{}).Where("session_id = ? AND parent_id = ?", sessionId, parentId).Order("id ASC").Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// branchCTE 从消息开始沿被选中的子消息向下，得到以它开头的分支上的全部消息 id
+const branchCTE = "WITH RECURSIVE branch AS (" +
+	"SELECT id FROM `message` WHERE id = ? " +
+	"UNION ALL " +
+	"SELECT m.id FROM `message` m JOIN branch b ON m.session_id = ? AND m.parent_id = b.id WHERE m.is_selected = 1" +
+	") SELECT id FROM branch"
+
+// SelectChild 将 id 设为 parentId 下唯一被选中的分支。parentId 在当前分支上（或为 0）时同时切换当前分支：
+// 原分支上 parentId 之后的消息不再属于当前分支，从 id 开始沿被选中的子消息向下的消息成为当前分支
+func (m *defaultMessageModel) SelectChild(ctx context.Context, tx *gorm.DB, sessionId int64, parentId int64, id int64) error {
+	siblings, err := m.FindChildren(ctx, sessionId, parentId)
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for _, sibling := range siblings {
+		keys = append(keys, m.getCacheKeys(sibling)...)
+	}
+	var changed []int64
+	err = m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		if err := db.Model(&Message{}).Where("session_id = ? AND parent_id = ?", sessionId, parentId).Update("is_selected", gorm.Expr("id = ?", id)).Error; err != nil {
+			return err
+		}
+		if parentId != 0 {
+			var active int64
+			if err := db.Model(&Message{}).Where("id = ? AND is_active = 1", parentId).Count(&active).Error; err != nil || active == 0 {
+				return err
+			}
+		}
+		// 当前分支上的消息 id 自根向下递增，parentId 之后的部分即 id 大于它的消息
+		var stale, branch []int64
+		if err := db.Model(&Message{}).Where("session_id = ? AND is_active = 1 AND id > ?", sessionId, parentId).Pluck("id", &stale).Error; err != nil {
+			return err
+		}
+		if err := db.Raw(branchCTE, id, sessionId).Scan(&branch).Error; err != nil {
+			return err
+		}
+		if len(stale) > 0 {
+			if err := db.Model(&Message{}).Where("id IN ?", stale).Update("is_active", 0).Error; err != nil {
+				return err
+			}
+		}
+		changed = append(stale, branch...)
+		return db.Model(&Message{}).Where("id IN ?", branch).Update("is_active", 1).Error
+	}, keys...)
+	if err != nil || len(changed) == 0 {
+		return err
+	}
+	keys = nil
+	for _, changedId := range changed {
+		keys = append(keys, m.getCacheKeys(&Message{Id: changedId})...)
+	}
+	return m.DelCacheCtx(ctx, keys...)
+}
+
+// CountByCharacter 统计角色所有会话中的消息数
+func (m *defaultMessageModel) CountByCharacter(ctx context.Context, characterId int64) (int64, error) {
+	var count int64
+	err := m.QueryNoCacheCtx(ctx, &count, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Message{}).Joins("JOIN `session` ON `session`.id = `message`.session_id").
+			Where("`session`.character_id = ?", characterId).Count(&count).Error
+	})
+	return count, err
+}
+
+// DeleteBySession 删除会话的全部消息
+func (m *defaultMessageModel) DeleteBySession(ctx context.Context, tx *gorm.DB, sessionId int64) error {
+	messages, err := m.FindBySession(ctx, sessionId)
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for _, msg := range messages {
+		keys = append(keys, m.getCacheKeys(msg)...)
+	}
+	return m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		return db.Where("session_id = ?", sessionId).Delete(&Message{}).Error
+	}, keys...)
+}
+
+const (
+	// ngramTokenSize 与 MySQL 的 ngram_token_size 一致
+	ngramTokenSize = 2
+)
+
+// SearchTerms 去掉关键词中的 BOOLEAN MODE 运算符后按空白拆分，返回实际参与搜索的词，空白分隔的每个词都需命中
+func SearchTerms(keyword string) []string {
+	keyword = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`+-<>()~*"@`, r) {
+			return ' '
+		}
+		return r
+	}, keyword)
+	return strings.Fields(keyword)
+}
+
+// HasIndexedTerm 关键词中是否有能走全文索引的词，只有短词时 LIKE 需扫描用户的全部消息，不允许搜索
+func HasIndexedTerm(keyword string) bool {
+	indexed, _ := searchTerms(keyword)
+	return len(indexed) > 0
+}
+
+// searchTerms 将 SearchTerms 拆成能走全文索引的词与短于 ngram 分词长度、不会被索引的词，后者用 LIKE 匹配
+func searchTerms(keyword string) (indexed []string, short []string) {
+	for _, word := range SearchTerms(keyword) {
+		if len([]rune(word)) >= ngramTokenSize {
+			indexed = append(indexed, word)
+		} else {
+			short = append(short, word)
+		}
+	}
+	return indexed, short
+}
+
+// booleanPhrase 将词转为 BOOLEAN MODE 的短语查询，每个词都需命中
+func booleanPhrase(words []string) string {
+	var query strings.Builder
+	for _, word := range words {
+		fmt.Fprintf(&query, `+"%s" `, word)
+	}
+	return strings.TrimSpace(query.String())
+}
+
+func escapeLike(keyword string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(keyword)
+}
@@ -25,10 +25,17 @@ type (
 		FindByQuery(ctx context.Context, cursor int64, pageSize int64, query map[string]interface{}) ([]*Message, error)
 		FuzzyFind(ctx context.Context, cursor int64, pageSize int64, title string, keyword string) ([]*Message, error)
 		FindBySession(ctx context.Context, sessionId int64) ([]*Message, error)
+		FindPage(ctx context.Context, sessionId int64, before int64, after int64, limit int) ([]*Message, error)
+		FindActiveRange(ctx context.Context, sessionId int64, after int64, before int64, limit int) ([]*Message, error)
+		Search(ctx context.Context, userId int64, keyword string, cursor int64, limit int) ([]*MessageHit, error)
+		CountByCharacter(ctx context.Context, characterId int64) (int64, error)
+		FindChildren(ctx context.Context, sessionId int64, parentId int64) ([]*Message, error)
+		SelectChild(ctx context.Context, tx *gorm.DB, sessionId int64, parentId int64, id int64) error
 
 		Update(ctx context.Context, tx *gorm.DB, data *Message) error
 
 		Delete(ctx context.Context, tx *gorm.DB, id int64) error
+		DeleteBySession(ctx context.Context, tx *gorm.DB, sessionId int64) error
 		Transaction(ctx context.Context, fn func(db *gorm.DB) error) error
 	}
 
@@ -38,12 +45,15 @@ type (
 	}
 
 	Message struct {
-		Id        int64     `gorm:"column:id" json:"id"`
-		SessionId int64     `gorm:"column:session_id" json:"session_id"` // 关联的会话ID
-		Role      string    `gorm:"column:role" json:"role"`             // 消息角色
-		Content   string    `gorm:"column:content" json:"content"`       // 消息内容
-		Metadata  string    `gorm:"column:metadata" json:"metadata"`
-		CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+		Id         int64     `gorm:"column:id" json:"id"`
+		SessionId  int64     `gorm:"column:session_id" json:"session_id"` // 关联的会话ID
+		ParentId   int64     `gorm:"column:parent_id" json:"parent_id"`   // 父消息ID，会话的第一条消息为0
+		Role       string    `gorm:"column:role" json:"role"`             // 消息角色
+		Content    string    `gorm:"column:content" json:"content"`       // 消息内容
+		Metadata   string    `gorm:"column:metadata" json:"metadata"`
+		IsSelected int64     `gorm:"column:is_selected" json:"is_selected"` // 是否为父消息下当前选中的分支
+		IsActive   int64     `gorm:"column:is_active" json:"is_active"`     // 是否在会话当前分支上
+		CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
 	}
 )
 
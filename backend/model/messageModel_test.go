@@ -0,0 +1,105 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SpectatorNan/gorm-zero/gormc"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// newDryRunMessageModel 只生成 SQL 不执行，返回模型与记录下的查询语句
+func newDryRunMessageModel(t *testing.T) (*defaultMessageModel, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:3306)/roletalk", SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var queries []string
+	record := func(db *gorm.DB) {
+		queries = append(queries, db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...))
+	}
+	if err = db.Callback().Query().After("gorm:query").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Callback().Row().After("gorm:row").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	return &defaultMessageModel{CachedConn: gormc.NewConnWithCache(db, nil), table: "`message`"}, &queries
+}
+
+func TestFindPageIsBoundedRangeQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after int64
+		want          string
+	}{
+		{"latest", 0, 0, "SELECT * FROM `message` WHERE session_id = 1 AND is_active = 1 ORDER BY id DESC LIMIT 51"},
+		{"before", 100, 0, "SELECT * FROM `message` WHERE (session_id = 1 AND is_active = 1) AND id < 100 ORDER BY id DESC LIMIT 51"},
+		{"after", 0, 100, "SELECT * FROM `message` WHERE (session_id = 1 AND is_active = 1) AND id > 100 ORDER BY id ASC LIMIT 51"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, queries := newDryRunMessageModel(t)
+			if _, err := m.FindPage(context.Background(), 1, tt.before, tt.after, 51); err != nil {
+				t.Fatal(err)
+			}
+			// 每页只在 (session_id, is_active, id) 索引上读取 limit 行，不递归遍历整棵消息树
+			if len(*queries) != 1 || (*queries)[0] != tt.want {
+				t.Fatalf("queries = %q, want %q", *queries, tt.want)
+			}
+			if strings.Contains((*queries)[0], "RECURSIVE") {
+				t.Fatal("page query walks the message tree")
+			}
+		})
+	}
+}
+
+func TestFindActiveRangeIsBoundedRangeQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		after, before int64
+		want          string
+	}{
+		{"from start", 0, 0, "SELECT * FROM `message` WHERE session_id = 1 AND is_active = 1 AND id > 0 ORDER BY id ASC LIMIT 50"},
+		{"between", 10, 100, "SELECT * FROM `message` WHERE (session_id = 1 AND is_active = 1 AND id > 10) AND id < 100 ORDER BY id ASC LIMIT 50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, queries := newDryRunMessageModel(t)
+			if _, err := m.FindActiveRange(context.Background(), 1, tt.after, tt.before, 50); err != nil {
+				t.Fatal(err)
+			}
+			if len(*queries) != 1 || (*queries)[0] != tt.want {
+				t.Fatalf("queries = %q, want %q", *queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchFiltersActiveBranch(t *testing.T) {
+	m, queries := newDryRunMessageModel(t)
+	// Scan 在 DryRun 模式下只生成 SQL，返回 ErrDryRunModeUnsupported
+	if _, err := m.Search(context.Background(), 1, "港口 a", 0, 21); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatal(err)
+	}
+	want := "SELECT m.*, s.title AS session_title, s.character_id, c.name AS character_name FROM `message` m " +
+		"JOIN `session` s ON s.id = m.session_id JOIN `character` c ON c.id = s.character_id AND c.deleted_at IS NULL " +
+		"WHERE (s.user_id = 1 AND m.is_active = 1) AND MATCH(m.content) AGAINST('+\"港口\"' IN BOOLEAN MODE) AND m.content LIKE '%a%' " +
+		"ORDER BY m.id DESC LIMIT 21"
+	if len(*queries) != 1 || (*queries)[0] != want {
+		t.Fatalf("queries = %q, want %q", *queries, want)
+	}
+
+	// 只有短词时 LIKE 没有可用的索引，不执行查询
+	*queries = nil
+	if hits, err := m.Search(context.Background(), 1, "a b", 0, 21); err != nil || len(hits) != 0 {
+		t.Fatalf("hits = %v, err = %v", hits, err)
+	}
+	if len(*queries) != 0 {
+		t.Fatalf("short-only keyword ran %q", *queries)
+	}
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"gorm.io/gorm"
+)
+
+var _ PersonaModel = (*customPersonaModel)(nil)
+
+type (
+	// PersonaModel is an interface to be customized, add more methods here,
+	// and implement the added methods in customPersonaModel.
+	PersonaModel interface {
+		personaModel
+		customPersonaLogicModel
+	}
+
+	customPersonaModel struct {
+		*defaultPersonaModel
+	}
+
+	customPersonaLogicModel interface {
+	}
+)
+
+// NewPersonaModel returns a model for the database table.
+func NewPersonaModel(conn *gorm.DB, c cache.CacheConf) PersonaModel {
+	return &customPersonaModel{
+		defaultPersonaModel: newPersonaModel(conn, c),
+	}
+}
+func (m *defaultPersonaModel) getNewModelNeedReloadCacheKeys(data *Persona) []string {
+	if data == nil {
+		return []string{}
+	}
+	return []string{}
+}
+func (m *defaultPersonaModel) customCacheKeys(data *Persona) []string {
+	if data == nil {
+		return []string{}
+	}
+	return []string{}
+}
+
+func (m *defaultPersonaModel) Find(ctx context.Context, cursor int64, pageSize int64) ([]*Persona, error) {
+	var resp []*Persona
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Persona{}).Limit(int(pageSize)).Offset(int(cursor)).Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *defaultPersonaModel) FindByQuery(ctx context.Context, cursor int64, pageSize int64, query map[string]interface{}) ([]*Persona, error) {
+	var resp []*Persona
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Persona{}).Where(query).Limit(int(pageSize)).Offset(int(cursor)).Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// FindByUser 返回用户的全部人设，按创建顺序排列
+func (m *defaultPersonaModel) FindByUser(ctx context.Context, userId int64) ([]*Persona, error) {
+	var resp []*Persona
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Persona{}).Where("user_id = ?", userId).Order("id ASC").Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
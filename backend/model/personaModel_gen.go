@@ -0,0 +1,137 @@
+// Code generated by goctl. DO NOT EDIT!
+
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SpectatorNan/gorm-zero/gormc"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"gorm.io/gorm"
+)
+
+var (
+	cacheRoletalkPersonaIdPrefix = "cache:roletalk:persona:id:"
+)
+
+type (
+	personaModel interface {
+		Insert(ctx context.Context, tx *gorm.DB, data *Persona) error
+
+		FindOne(ctx context.Context, id int64) (*Persona, error)
+		Find(ctx context.Context, cursor int64, pageSize int64) ([]*Persona, error)
+		FindByQuery(ctx context.Context, cursor int64, pageSize int64, query map[string]interface{}) ([]*Persona, error)
+		FindByUser(ctx context.Context, userId int64) ([]*Persona, error)
+
+		Update(ctx context.Context, tx *gorm.DB, data *Persona) error
+
+		Delete(ctx context.Context, tx *gorm.DB, id int64) error
+		Transaction(ctx context.Context, fn func(db *gorm.DB) error) error
+	}
+
+	defaultPersonaModel struct {
+		gormc.CachedConn
+		table string
+	}
+
+	Persona struct {
+		Id          int64          `gorm:"column:id"`
+		UserId      int64          `gorm:"column:user_id"`
+		Name        string         `gorm:"column:name"`        // 对话中角色对用户的称呼
+		Description string         `gorm:"column:description"` // 用户在角色扮演中的身份设定
+		CreatedAt   time.Time      `gorm:"column:created_at"`
+		UpdatedAt   time.Time      `gorm:"column:updated_at"`
+		DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;index"`
+	}
+)
+
+func (Persona) TableName() string {
+	return "`persona`"
+}
+
+func newPersonaModel(conn *gorm.DB, c cache.CacheConf) *defaultPersonaModel {
+	return &defaultPersonaModel{
+		CachedConn: gormc.NewConn(conn, c),
+		table:      "`persona`",
+	}
+}
+
+func (m *defaultPersonaModel) Insert(ctx context.Context, tx *gorm.DB, data *Persona) error {
+
+	err := m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		return db.Save(&data).Error
+	}, m.getCacheKeys(data)...)
+	return err
+}
+
+func (m *defaultPersonaModel) FindOne(ctx context.Context, id int64) (*Persona, error) {
+	roletalkPersonaIdKey := fmt.Sprintf("%s%v", cacheRoletalkPersonaIdPrefix, id)
+	var resp Persona
+	err := m.QueryCtx(ctx, &resp, roletalkPersonaIdKey, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Persona{}).Where("`id` = ?", id).First(&resp).Error
+	})
+	switch err {
+	case nil:
+		return &resp, nil
+	case gormc.ErrNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultPersonaModel) Update(ctx context.Context, tx *gorm.DB, data *Persona) error {
+	old, err := m.FindOne(ctx, data.Id)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	clearKeys := append(m.getCacheKeys(old), m.getNewModelNeedReloadCacheKeys(data)...)
+	err = m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		return db.Save(data).Error
+	}, clearKeys...)
+	return err
+}
+
+func (m *defaultPersonaModel) getCacheKeys(data *Persona) []string {
+	if data == nil {
+		return []string{}
+	}
+	roletalkPersonaIdKey := fmt.Sprintf("%s%v", cacheRoletalkPersonaIdPrefix, data.Id)
+	cacheKeys := []string{
+		roletalkPersonaIdKey,
+	}
+	cacheKeys = append(cacheKeys, m.customCacheKeys(data)...)
+	return cacheKeys
+}
+
+func (m *defaultPersonaModel) Delete(ctx context.Context, tx *gorm.DB, id int64) error {
+	data, err := m.FindOne(ctx, id)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	err = m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		return db.Delete(&Persona{}, id).Error
+	}, m.getCacheKeys(data)...)
+	return err
+}
+
+func (m *defaultPersonaModel) Transaction(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return m.TransactCtx(ctx, fn)
+}
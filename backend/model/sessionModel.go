@@ -5,10 +5,18 @@ import (
 	"fmt"
 	"github.com/zeromicro/go-zero/core/stores/cache"
 	"gorm.io/gorm"
+	"time"
 )
 
 var _ SessionModel = (*customSessionModel)(nil)
 
+// 会话标题来源：截取开场白、LLM 根据对话生成、用户修改
+const (
+	SessionTitleOpening int64 = iota
+	SessionTitleGenerated
+	SessionTitleManual
+)
+
 type (
 	// SessionModel is an interface to be customized, add more methods here,
 	// and implement the added methods in customSessionModel.
@@ -77,3 +85,105 @@ func (m *defaultSessionModel) FuzzyFind(ctx context.Context, cursor int64, pageS
 	}
 	return resp, nil
 }
+
+// UpdateColumns 只更新指定的列，不影响 updated_at，供后台任务与其他写入并发时使用。
+// updated_at 为会话的最近活动时间，显式赋为原值，避免表上的 ON UPDATE CURRENT_TIMESTAMP 改变它
+func (m *defaultSessionModel) UpdateColumns(ctx context.Context, tx *gorm.DB, id int64, columns map[string]interface{}) error {
+	columns = keepUpdatedAt(columns)
+	return m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		return db.Model(&Session{}).Where("id = ?", id).UpdateColumns(columns).Error
+	}, m.getCacheKeys(&Session{Id: id})...)
+}
+
+// SessionFilter 会话列表的筛选条件
+type SessionFilter struct {
+	CharacterId int64 // 0 表示不限角色
+	Archived    bool  // 为 true 时只返回已归档的会话，否则只返回未归档的会话
+}
+
+// SessionCursor 会话列表的游标，为上一页最后一个会话的排序键
+type SessionCursor struct {
+	IsPinned  int64
+	UpdatedAt time.Time
+	Id        int64
+}
+
+// FindByUser 按筛选条件返回用户的会话，置顶的会话在前，其余按最近活动时间倒序，id 倒序区分活动时间相同的会话。
+// after 不为 nil 时只返回排在它之后的会话，翻页期间会话的活动时间变化不会导致其余会话被跳过或重复
+func (m *defaultSessionModel) FindByUser(ctx context.Context, userId int64, filter SessionFilter, after *SessionCursor, pageSize int64) ([]*Session, error) {
+	var resp []*Session
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		db := conn.Model(&Session{}).Where("user_id = ? AND is_archived = ?", userId, filter.Archived)
+		if filter.CharacterId != 0 {
+			db = db.Where("character_id = ?", filter.CharacterId)
+		}
+		if after != nil {
+			db = db.Where("is_pinned < ? OR (is_pinned = ? AND (updated_at < ? OR (updated_at = ? AND id < ?)))",
+				after.IsPinned, after.IsPinned, after.UpdatedAt, after.UpdatedAt, after.Id)
+		}
+		return db.Order("is_pinned DESC, updated_at DESC, id DESC").Limit(int(pageSize)).Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Touch 将会话的最近活动时间更新为当前时间，写入新消息时在同一事务中调用。
+// 会话已被删除时返回 ErrNotFound，使写入新消息的事务回滚
+func (m *defaultSessionModel) Touch(ctx context.Context, tx *gorm.DB, id int64) error {
+	return m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		db := conn
+		if tx != nil {
+			db = tx
+		}
+		res := db.Model(&Session{}).Where("id = ?", id).UpdateColumn("updated_at", time.Now())
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+		// 时间未变化时 MySQL 同样返回 0 行，需确认会话是否存在
+		var count int64
+		if err := db.Model(&Session{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		return nil
+	}, m.getCacheKeys(&Session{Id: id})...)
+}
+
+// UpdateGeneratedTitle 写入自动生成的标题，标题已被生成或被用户修改时不写入
+func (m *defaultSessionModel) UpdateGeneratedTitle(ctx context.Context, id int64, title string) error {
+	return m.ExecCtx(ctx, func(conn *gorm.DB) error {
+		return conn.Model(&Session{}).Where("id = ? AND title_source = ?", id, SessionTitleOpening).
+			UpdateColumns(keepUpdatedAt(map[string]interface{}{
+				"title":        title,
+				"title_source": SessionTitleGenerated,
+			})).Error
+	}, m.getCacheKeys(&Session{Id: id})...)
+}
+
+func keepUpdatedAt(columns map[string]interface{}) map[string]interface{} {
+	if _, ok := columns["updated_at"]; !ok {
+		columns["updated_at"] = gorm.Expr("updated_at")
+	}
+	return columns
+}
+
+// CountByCharacter 统计角色的会话数与参与对话的用户数
+func (m *defaultSessionModel) CountByCharacter(ctx context.Context, characterId int64) (sessions int64, users int64, err error) {
+	var resp struct {
+		Sessions int64
+		Users    int64
+	}
+	err = m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Session{}).Select("COUNT(*) AS sessions, COUNT(DISTINCT user_id) AS users").
+			Where("character_id = ?", characterId).Scan(&resp).Error
+	})
+	return resp.Sessions, resp.Users, err
+}
@@ -24,8 +24,13 @@ type (
 		Find(ctx context.Context, cursor int64, pageSize int64) ([]*Session, error)
 		FindByQuery(ctx context.Context, cursor int64, pageSize int64, query map[string]interface{}) ([]*Session, error)
 		FuzzyFind(ctx context.Context, cursor int64, pageSize int64, title string, keyword string) ([]*Session, error)
+		FindByUser(ctx context.Context, userId int64, filter SessionFilter, after *SessionCursor, pageSize int64) ([]*Session, error)
+		CountByCharacter(ctx context.Context, characterId int64) (sessions int64, users int64, err error)
 
 		Update(ctx context.Context, tx *gorm.DB, data *Session) error
+		UpdateColumns(ctx context.Context, tx *gorm.DB, id int64, columns map[string]interface{}) error
+		UpdateGeneratedTitle(ctx context.Context, id int64, title string) error
+		Touch(ctx context.Context, tx *gorm.DB, id int64) error
 
 		Delete(ctx context.Context, tx *gorm.DB, id int64) error
 		Transaction(ctx context.Context, fn func(db *gorm.DB) error) error
@@ -37,12 +42,19 @@ type (
 	}
 
 	Session struct {
-		Id          int64     `gorm:"column:id"`
-		CharacterId int64     `gorm:"column:character_id"` // 关联的角色ID
-		UserId      int64     `gorm:"column:user_id"`      // 用户ID
-		Title       string    `gorm:"column:title"`        // 会话标题，例如第一句话或摘要
-		CreatedAt   time.Time `gorm:"column:created_at"`
-		UpdatedAt   time.Time `gorm:"column:updated_at"`
+		Id            int64     `gorm:"column:id"`
+		CharacterId   int64     `gorm:"column:character_id"`   // 关联的角色ID
+		UserId        int64     `gorm:"column:user_id"`        // 用户ID
+		PersonaId     int64     `gorm:"column:persona_id"`     // 用户在会话中使用的人设ID，0 表示使用个人资料
+		Title         string    `gorm:"column:title"`          // 会话标题，例如第一句话或摘要
+		TitleSource   int64     `gorm:"column:title_source"`   // 标题来源：0 开场白 1 自动生成 2 用户修改
+		MemoryCursor  int64     `gorm:"column:memory_cursor"`  // 已提取长期记忆的最后一条消息ID
+		Summary       string    `gorm:"column:summary"`        // 早于最近消息窗口的剧情摘要
+		SummaryCursor int64     `gorm:"column:summary_cursor"` // 摘要已覆盖的最后一条消息ID
+		IsArchived    int64     `gorm:"column:is_archived"`    // 是否已归档
+		IsPinned      int64     `gorm:"column:is_pinned"`      // 是否置顶
+		CreatedAt     time.Time `gorm:"column:created_at"`
+		UpdatedAt     time.Time `gorm:"column:updated_at"`
 	}
 )
 
@@ -66,6 +66,21 @@ func (m *defaultTagModel) FindAll(ctx context.Context) ([]*Tag, error) {
 	return resp, nil
 }
 
+// FindByIds 一次查询多个标签，已删除的标签不返回
+func (m *defaultTagModel) FindByIds(ctx context.Context, ids []int64) ([]*Tag, error) {
+	var resp []*Tag
+	if len(ids) == 0 {
+		return resp, nil
+	}
+	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
+		return conn.Model(&Tag{}).Where("id IN ?", ids).Find(&resp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (m *defaultTagModel) FindByQuery(ctx context.Context, cursor int64, pageSize int64, query map[string]interface{}) ([]*Tag, error) {
 	var resp []*Tag
 	err := m.QueryNoCacheCtx(ctx, &resp, func(conn *gorm.DB, v interface{}) error {
@@ -24,6 +24,7 @@ type (
 		FindOne(ctx context.Context, id int64) (*Tag, error)
 		Find(ctx context.Context, cursor int64, pageSize int64) ([]*Tag, error)
 		FindAll(ctx context.Context) ([]*Tag, error)
+		FindByIds(ctx context.Context, ids []int64) ([]*Tag, error)
 		FindByQuery(ctx context.Context, cursor int64, pageSize int64, query map[string]interface{}) ([]*Tag, error)
 		FuzzyFind(ctx context.Context, cursor int64, pageSize int64, title string, keyword string) ([]*Tag, error)
 